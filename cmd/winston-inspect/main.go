@@ -0,0 +1,109 @@
+// Command winston-inspect print the compression statistics of a serialized chunk,
+// which helps tuning the bucket widths of each precision against real data.
+//
+// usage:
+//
+//	winston-inspect [-precision 1s] <chunk file | ->
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dtynn/winston/pkg/chunk"
+)
+
+func main() {
+	precision := flag.Duration("precision", time.Second, "precision of a raw bit stream, ignored for framed chunks")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <chunk file | ->\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := inspect(os.Stdout, flag.Arg(0), *precision); err != nil {
+		fmt.Fprintf(os.Stderr, "winston-inspect: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func inspect(out io.Writer, path string, precision time.Duration) error {
+	var data []byte
+	var err error
+
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if h, err := chunk.ReadHeader(data); err == nil {
+		fmt.Fprintf(out, "format version:  %d\n", h.Version)
+		fmt.Fprintf(out, "time range:      [%d, %d]\n", h.MinTime, h.MaxTime)
+		fmt.Fprintf(out, "lossy:           %s\n", h.Lossy)
+		precision = h.Precision
+	}
+
+	iter, err := chunk.NewIter(data, precision)
+	if err != nil {
+		return err
+	}
+
+	iter.PointStat(true)
+	for iter.Next() {
+	}
+
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	stats := iter.Stats()
+
+	fmt.Fprintf(out, "precision:       %s\n", precision)
+	fmt.Fprintf(out, "value type:      %s\n", iter.Type())
+	fmt.Fprintf(out, "size:            %d bytes\n", len(data))
+	fmt.Fprintf(out, "points:          %d\n", stats.Points)
+	fmt.Fprintf(out, "timestamp bits:  %d (%.2f per point)\n", stats.TimestampBits, stats.TimestampBitsPerPoint())
+	fmt.Fprintf(out, "value bits:      %d (%.2f per point)\n", stats.ValueBits, stats.ValueBitsPerPoint())
+	fmt.Fprintf(out, "bits per point:  %.2f\n", stats.BitsPerPoint())
+
+	fmt.Fprintln(out, "\ndod control bits:")
+	histogram(out, stats.DoD)
+
+	fmt.Fprintln(out, "\nvalue control bits:")
+	histogram(out, stats.Value)
+
+	return nil
+}
+
+func histogram(out io.Writer, cases map[uint64]int) {
+	total := 0
+	keys := make([]uint64, 0, len(cases))
+	for k, n := range cases {
+		keys = append(keys, k)
+		total += n
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+
+	for _, k := range keys {
+		n := cases[k]
+		fmt.Fprintf(out, "  %-6s %10d  %6.2f%%\n", chunk.ControlBits(k), n, float64(n)*100/float64(total))
+	}
+}
@@ -0,0 +1,229 @@
+// Package aggregate computes statistics over chunks without materializing the points.
+package aggregate
+
+import (
+	"errors"
+	"math"
+
+	"github.com/dtynn/winston/pkg/chunk"
+)
+
+var (
+	// ErrNotNumeric the values can not be aggregated
+	ErrNotNumeric = errors.New("values are not numeric")
+
+	// ErrInvalidStep the step of buckets is not positive
+	ErrInvalidStep = errors.New("invalid step")
+
+	// ErrTooManyBuckets the range would be split into more than MaxBuckets buckets
+	ErrTooManyBuckets = errors.New("too many buckets")
+)
+
+// MaxBuckets the max number of buckets returned by Downsample
+const MaxBuckets = 1 << 20
+
+// Func aggregation function
+type Func uint8
+
+const (
+	// Min minimum value
+	Min Func = iota
+	// Max maximum value
+	Max
+	// Sum sum of values
+	Sum
+	// Count number of points
+	Count
+	// Avg average value
+	Avg
+	// First value of the earliest point
+	First
+	// Last value of the latest point
+	Last
+	// Stddev population standard deviation
+	Stddev
+)
+
+// Of return the aggregated value from the summary, NaN for empty summary except Count
+func (f Func) Of(s chunk.Summary) float64 {
+	if f == Count {
+		return float64(s.Count)
+	}
+
+	if s.Count == 0 {
+		return math.NaN()
+	}
+
+	switch f {
+	case Min:
+		return s.Min
+
+	case Max:
+		return s.Max
+
+	case Sum:
+		return s.Sum
+
+	case Avg:
+		return s.Avg()
+
+	case First:
+		return s.First
+
+	case Last:
+		return s.Last
+
+	case Stddev:
+		return s.Stddev()
+
+	default:
+		return math.NaN()
+	}
+}
+
+// Bucket summary of points in [Start, Start+step)
+type Bucket struct {
+	Start int64
+	chunk.Summary
+}
+
+// Range aggregate points of the iterator in [start, end)
+func Range(it *chunk.Iter, start, end int64) (chunk.Summary, error) {
+	var s chunk.Summary
+
+	if it.Type() == chunk.TypeString {
+		return s, ErrNotNumeric
+	}
+
+	if !it.SeekTo(start) {
+		return s, it.Err()
+	}
+
+	for {
+		t, vbits := it.Point()
+		if t >= end {
+			break
+		}
+
+		v, _ := it.Type().Float64(vbits)
+		s.Add(t, v)
+
+		if !it.Next() {
+			break
+		}
+	}
+
+	return s, it.Err()
+}
+
+// Chunk aggregate points of the chunk in [start, end),
+// the precomputed summary is used if the whole chunk is in range
+func Chunk(c *chunk.Chunk, start, end int64) (chunk.Summary, error) {
+	if c.Num() > 0 && start <= c.MinTime() && c.MaxTime() < end {
+		if s := c.Summary(); s.Count == c.Num() {
+			return s, nil
+		}
+	}
+
+	it, err := c.Iter()
+	if err != nil {
+		return chunk.Summary{}, err
+	}
+
+	return Range(it, start, end)
+}
+
+// Data aggregate points of a serialized chunk in [start, end),
+// the summary in the header is used if the whole chunk is in range
+func Data(data []byte, start, end int64) (chunk.Summary, error) {
+	h, err := chunk.ReadHeader(data)
+	if err != nil {
+		return chunk.Summary{}, err
+	}
+
+	if h.Type == chunk.TypeString {
+		return chunk.Summary{}, ErrNotNumeric
+	}
+
+	if h.Num == 0 || h.MaxTime < start || h.MinTime >= end {
+		return chunk.Summary{}, nil
+	}
+
+	if start <= h.MinTime && h.MaxTime < end && h.Summary.Count == h.Num {
+		return h.Summary, nil
+	}
+
+	it, err := chunk.NewIter(data, h.Precision)
+	if err != nil {
+		return chunk.Summary{}, err
+	}
+
+	return Range(it, start, end)
+}
+
+// Chunks aggregate points of several chunks in [start, end)
+func Chunks(cks []*chunk.Chunk, start, end int64) (chunk.Summary, error) {
+	var s chunk.Summary
+	for _, c := range cks {
+		cs, err := Chunk(c, start, end)
+		if err != nil {
+			return s, err
+		}
+
+		s.Merge(cs)
+	}
+
+	return s, nil
+}
+
+// Downsample aggregate points of the iterator in [start, end) into fixed-width buckets,
+// ErrTooManyBuckets is returned if there would be more than MaxBuckets of them
+func Downsample(it *chunk.Iter, start, end, step int64) ([]Bucket, error) {
+	if step <= 0 {
+		return nil, ErrInvalidStep
+	}
+
+	if it.Type() == chunk.TypeString {
+		return nil, ErrNotNumeric
+	}
+
+	if end <= start {
+		return nil, nil
+	}
+
+	// the distance may not fit in an int64
+	span := uint64(end) - uint64(start)
+	n := span / uint64(step)
+	if span%uint64(step) != 0 {
+		n++
+	}
+
+	if n > MaxBuckets {
+		return nil, ErrTooManyBuckets
+	}
+
+	buckets := make([]Bucket, n)
+	for i := range buckets {
+		buckets[i].Start = start + int64(i)*step
+	}
+
+	if !it.SeekTo(start) {
+		return buckets, it.Err()
+	}
+
+	for {
+		t, vbits := it.Point()
+		if t >= end {
+			break
+		}
+
+		v, _ := it.Type().Float64(vbits)
+		buckets[(t-start)/step].Add(t, v)
+
+		if !it.Next() {
+			break
+		}
+	}
+
+	return buckets, it.Err()
+}
@@ -0,0 +1,243 @@
+package aggregate
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/dtynn/winston/pkg/chunk"
+)
+
+func almostEqual(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+func bruteForce(ts []int64, vals []float64, start, end int64) map[Func]float64 {
+	res := map[Func]float64{
+		Min:    math.NaN(),
+		Max:    math.NaN(),
+		Sum:    math.NaN(),
+		Avg:    math.NaN(),
+		First:  math.NaN(),
+		Last:   math.NaN(),
+		Stddev: math.NaN(),
+	}
+
+	var in []float64
+	for i, t := range ts {
+		if t < start || t >= end {
+			continue
+		}
+
+		if len(in) == 0 {
+			res[First] = vals[i]
+			res[Min], res[Max] = vals[i], vals[i]
+		}
+
+		res[Min] = math.Min(res[Min], vals[i])
+		res[Max] = math.Max(res[Max], vals[i])
+		res[Last] = vals[i]
+		in = append(in, vals[i])
+	}
+
+	res[Count] = float64(len(in))
+	if len(in) == 0 {
+		return res
+	}
+
+	var sum float64
+	for _, v := range in {
+		sum += v
+	}
+
+	avg := sum / float64(len(in))
+
+	var sq float64
+	for _, v := range in {
+		sq += (v - avg) * (v - avg)
+	}
+
+	res[Sum] = sum
+	res[Avg] = avg
+	res[Stddev] = math.Sqrt(sq / float64(len(in)))
+	return res
+}
+
+func newTestChunk(t *testing.T, baseT time.Time, pointNum int) (*chunk.Chunk, []int64, []float64) {
+	c := chunk.NewChunk(baseT, chunk.Checkpoints(128))
+
+	ts := make([]int64, pointNum)
+	vals := make([]float64, pointNum)
+	tm := baseT.Unix()
+	for i := range ts {
+		tm += 1 + rand.Int63n(10)
+		ts[i] = tm
+		vals[i] = math.Round(rand.NormFloat64()*1000) / 10
+
+		if err := c.PushFloat64(ts[i], vals[i]); err != nil {
+			t.Fatalf("push %s", err)
+		}
+	}
+
+	return c, ts, vals
+}
+
+var funcs = []Func{Min, Max, Sum, Count, Avg, First, Last, Stddev}
+
+func TestRange(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+	c, ts, vals := newTestChunk(t, baseT, 5000)
+
+	for i := 0; i < 50; i++ {
+		start := ts[0] - 10 + rand.Int63n(ts[len(ts)-1]-ts[0]+20)
+		end := start + rand.Int63n(ts[len(ts)-1]-start+20)
+		if i == 0 {
+			start, end = ts[0], ts[len(ts)-1]+1
+		}
+
+		expected := bruteForce(ts, vals, start, end)
+
+		it, err := c.Iter()
+		if err != nil {
+			t.Fatalf("new iter %s", err)
+		}
+
+		s, err := Range(it, start, end)
+		if err != nil {
+			t.Fatalf("#%d range %s", i+1, err)
+		}
+
+		cs, err := Chunk(c, start, end)
+		if err != nil {
+			t.Fatalf("#%d chunk %s", i+1, err)
+		}
+
+		for _, fn := range funcs {
+			if got := fn.Of(s); !almostEqual(got, expected[fn]) {
+				t.Fatalf("#%d [%d, %d) func %d expected %v, got %v", i+1, start, end, fn, expected[fn], got)
+			}
+
+			if got := fn.Of(cs); !almostEqual(got, expected[fn]) {
+				t.Fatalf("#%d [%d, %d) func %d from chunk expected %v, got %v", i+1, start, end, fn, expected[fn], got)
+			}
+		}
+	}
+}
+
+func TestWholeChunk(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+	var cks []*chunk.Chunk
+	var allTs []int64
+	var allVals []float64
+
+	for d := 0; d < 3; d++ {
+		c, ts, vals := newTestChunk(t, baseT.Add(time.Duration(d)*24*time.Hour), 1000)
+		cks = append(cks, c)
+		allTs = append(allTs, ts...)
+		allVals = append(allVals, vals...)
+	}
+
+	start, end := allTs[0], allTs[len(allTs)-1]+1
+	expected := bruteForce(allTs, allVals, start, end)
+
+	s, err := Chunks(cks, start, end)
+	if err != nil {
+		t.Fatalf("chunks %s", err)
+	}
+
+	for _, fn := range funcs {
+		if got := fn.Of(s); !almostEqual(got, expected[fn]) {
+			t.Fatalf("func %d expected %v, got %v", fn, expected[fn], got)
+		}
+	}
+
+	data, err := cks[0].MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal binary %s", err)
+	}
+
+	h, err := chunk.ReadHeader(data)
+	if err != nil {
+		t.Fatalf("read header %s", err)
+	}
+
+	if h.Summary.Count != 1000 {
+		t.Fatalf("expected summary of 1000 points in header, got %d", h.Summary.Count)
+	}
+
+	expected = bruteForce(allTs[:1000], allVals[:1000], start, end)
+	ds, err := Data(data, start, end)
+	if err != nil {
+		t.Fatalf("data %s", err)
+	}
+
+	for _, fn := range funcs {
+		if got := fn.Of(ds); !almostEqual(got, expected[fn]) {
+			t.Fatalf("func %d from header expected %v, got %v", fn, expected[fn], got)
+		}
+	}
+
+	mid := allTs[500]
+	expected = bruteForce(allTs[:1000], allVals[:1000], mid, end)
+	ds, err = Data(data, mid, end)
+	if err != nil {
+		t.Fatalf("data %s", err)
+	}
+
+	if got := Sum.Of(ds); !almostEqual(got, expected[Sum]) {
+		t.Fatalf("partial sum expected %v, got %v", expected[Sum], got)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+	c, ts, vals := newTestChunk(t, baseT, 5000)
+
+	start, end := ts[100]-3, ts[4000]
+	step := int64(60)
+
+	it, err := c.Iter()
+	if err != nil {
+		t.Fatalf("new iter %s", err)
+	}
+
+	buckets, err := Downsample(it, start, end, step)
+	if err != nil {
+		t.Fatalf("downsample %s", err)
+	}
+
+	if expected := int((end - start + step - 1) / step); len(buckets) != expected {
+		t.Fatalf("expected %d buckets, got %d", expected, len(buckets))
+	}
+
+	for i, b := range buckets {
+		bend := b.Start + step
+		if bend > end {
+			bend = end
+		}
+
+		expected := bruteForce(ts, vals, b.Start, bend)
+		for _, fn := range funcs {
+			if got := fn.Of(b.Summary); !almostEqual(got, expected[fn]) {
+				t.Fatalf("bucket #%d func %d expected %v, got %v", i+1, fn, expected[fn], got)
+			}
+		}
+	}
+
+	if _, err := Downsample(it, start, end, 0); err != ErrInvalidStep {
+		t.Fatalf("expected ErrInvalidStep, got %v", err)
+	}
+
+	if _, err := Downsample(it, start, start+MaxBuckets*step+1, step); err != ErrTooManyBuckets {
+		t.Fatalf("expected ErrTooManyBuckets, got %v", err)
+	}
+
+	if _, err := Downsample(it, math.MinInt64, math.MaxInt64, 1); err != ErrTooManyBuckets {
+		t.Fatalf("expected ErrTooManyBuckets for the full range, got %v", err)
+	}
+}
@@ -26,3 +26,19 @@ func (b *breader) read(data interface{}) {
 		b.err = binary.Read(b.Reader, binary.BigEndian, data)
 	}
 }
+
+// readBytes read n bytes, which are rejected without allocating if the reader holds fewer bytes
+func (b *breader) readBytes(n uint64) []byte {
+	if b.err != nil {
+		return nil
+	}
+
+	if l, ok := b.Reader.(interface{ Len() int }); ok && uint64(l.Len()) < n {
+		b.err = io.ErrUnexpectedEOF
+		return nil
+	}
+
+	buf := make([]byte, n)
+	b.read(buf)
+	return buf
+}
@@ -3,7 +3,9 @@ package chunk
 import (
 	"bytes"
 	"encoding"
+	"encoding/binary"
 	"io"
+	"math/bits"
 )
 
 var (
@@ -19,23 +21,46 @@ const (
 )
 
 func newBStream(capacity int) *bstream {
-	return newBStreamWithData(make([]byte, 0, capacity))
+	return newBStreamWithData(getBuffer(capacity))
 }
 
 func newBStreamWithData(data []byte) *bstream {
 	return &bstream{
 		stream: data,
 		wBit:   0,
-		rIdx:   0,
-		rBit:   8,
 	}
 }
 
+// bstream bit stream.
+// bits are read through a 64-bit buffer, which is refilled a word at a time,
+// so a stream must not be written after reading started
 type bstream struct {
 	stream []byte
 	wBit   uint8
+
+	// bits not consumed yet are kept left-aligned in rBuf,
+	// rIdx is the next byte to be loaded into it
+	rBuf   uint64
+	rValid uint8
 	rIdx   int
-	rBit   uint8
+
+	// the stream is shared with a writer, whose last byte is read from the copy
+	shared bool
+	last   byte
+
+	// source to refill the stream from, consumed bytes are dropped on refill
+	r       io.Reader
+	dropped uint64
+
+	// accountant of the buffer capacity, nil if not accounted
+	acct *Accountant
+}
+
+// newBStreamReader return a bstream reading bits from r
+func newBStreamReader(r io.Reader) *bstream {
+	bs := newBStream(0)
+	bs.r = r
+	return bs
 }
 
 func (bs *bstream) clone() *bstream {
@@ -44,18 +69,101 @@ func (bs *bstream) clone() *bstream {
 	return &bstream{
 		stream: stream,
 		wBit:   bs.wBit,
+		rBuf:   bs.rBuf,
+		rValid: bs.rValid,
 		rIdx:   bs.rIdx,
-		rBit:   bs.rBit,
 	}
 }
 
+// reader return a bstream reading the bits written so far without copying the stream,
+// the writer may keep writing, the last byte it modifies is read from a copy.
+// must be called with the writer excluded
+func (bs *bstream) reader() *bstream {
+	r := &bstream{
+		stream: bs.stream[:len(bs.stream):len(bs.stream)],
+		wBit:   bs.wBit,
+		shared: true,
+	}
+
+	if n := len(r.stream); n > 0 {
+		r.last = r.stream[n-1]
+	}
+
+	return r
+}
+
 func (bs *bstream) rewind() {
-	bs.rIdx = 0
-	bs.rBit = 8
+	bs.seek(0)
+}
+
+// writeOffset return the number of bits written
+func (bs *bstream) writeOffset() uint64 {
+	return uint64(len(bs.stream))*8 - uint64(bs.wBit)
+}
+
+// readOffset return the number of bits read
+func (bs *bstream) readOffset() uint64 {
+	return (bs.dropped+uint64(bs.rIdx))*8 - uint64(bs.rValid)
+}
+
+// seek move the read position to the given bit offset, which is capped by the written bits
+func (bs *bstream) seek(off uint64) {
+	if off < bs.dropped*8 {
+		off = bs.dropped * 8
+	}
+
+	off -= bs.dropped * 8
+	if written := bs.writeOffset(); off > written {
+		off = written
+	}
+
+	bs.rIdx = int(off / 8)
+	bs.rBuf = 0
+	bs.rValid = 0
+
+	if skip := uint8(off % 8); skip > 0 {
+		bs.refill()
+		if bs.rValid >= skip {
+			bs.rBuf <<= skip
+			bs.rValid -= skip
+		}
+	}
+}
+
+// grow move the stream into a pooled buffer of the next size class.
+// the old buffer is left to the gc, since it may still be referenced by published views
+func (bs *bstream) grow() {
+	n := 2 * cap(bs.stream)
+	if n < classSize(0) {
+		n = classSize(0)
+	}
+
+	stream := append(getBuffer(n), bs.stream...)
+	if bs.acct != nil {
+		bs.acct.add(int64(cap(stream) - cap(bs.stream)))
+	}
+
+	bs.stream = stream
+}
+
+// release return the buffer to the pool, the stream must not be used any more
+func (bs *bstream) release() {
+	if bs.acct != nil {
+		bs.acct.add(-int64(cap(bs.stream)))
+		bs.acct = nil
+	}
+
+	putBuffer(bs.stream)
+	bs.stream = nil
+	bs.wBit = 0
 }
 
 func (bs *bstream) writeBit(bit bit) {
 	if bs.wBit == 0 {
+		if len(bs.stream) == cap(bs.stream) {
+			bs.grow()
+		}
+
 		bs.stream = append(bs.stream, 0)
 		bs.wBit = 8
 	}
@@ -68,6 +176,10 @@ func (bs *bstream) writeBit(bit bit) {
 }
 
 func (bs *bstream) writeByte(b byte) {
+	if len(bs.stream) == cap(bs.stream) {
+		bs.grow()
+	}
+
 	if bs.wBit == 0 {
 		bs.stream = append(bs.stream, b)
 		return
@@ -79,101 +191,230 @@ func (bs *bstream) writeByte(b byte) {
 	bs.stream[len(bs.stream)-1] |= b << bs.wBit
 }
 
+// writeBits write the lowest nbits of u, at most 64
 func (bs *bstream) writeBits(u uint64, nbits uint) {
-	u <<= (64 - nbits)
-	for nbits >= 8 {
-		bs.writeByte(byte(u >> 56))
+	if nbits == 0 {
+		return
+	}
+
+	if cap(bs.stream)-len(bs.stream) < 9 {
+		bs.grow()
+	}
+
+	u <<= 64 - nbits
+
+	// fill the free bits of the last byte, lower bits of u are zeros
+	if bs.wBit > 0 {
+		free := uint(bs.wBit)
+		bs.stream[len(bs.stream)-1] |= byte(u >> (64 - free))
+		if nbits <= free {
+			bs.wBit -= uint8(nbits)
+			return
+		}
+
+		u <<= free
+		nbits -= free
+		bs.wBit = 0
+	}
+
+	for ; nbits >= 8; nbits -= 8 {
+		bs.stream = append(bs.stream, byte(u>>56))
 		u <<= 8
-		nbits -= 8
 	}
 
-	for nbits > 0 {
-		bs.writeBit((u >> 63) == 1)
-		u <<= 1
-		nbits--
+	if nbits > 0 {
+		bs.stream = append(bs.stream, byte(u>>56))
+		bs.wBit = uint8(8 - nbits)
 	}
 }
 
-func (bs *bstream) checkReadBitSize() error {
-	written := len(bs.stream)*8 - int(bs.wBit)
-	read := (bs.rIdx+1)*8 - int(bs.rBit)
-	if read >= written {
-		return io.EOF
+func (bs *bstream) at(idx int) byte {
+	if bs.shared && idx == len(bs.stream)-1 {
+		return bs.last
 	}
 
-	return nil
+	return bs.stream[idx]
 }
 
-func (bs *bstream) readBit() (bit, error) {
-	if err := bs.checkReadBitSize(); err != nil {
-		return false, err
+// refill load as many bytes as possible into the read buffer
+func (bs *bstream) refill() {
+	end := len(bs.stream)
+	if bs.shared {
+		// the last byte is read from the copy
+		end--
+	}
+
+	// the last byte may be partially written, leave it to the slow path
+	if bs.rIdx+8 < end {
+		// bits following the loaded bytes are or-ed in as well,
+		// which is harmless since they will be loaded again at the same position
+		bs.rBuf |= binary.BigEndian.Uint64(bs.stream[bs.rIdx:]) >> bs.rValid
+		n := (64 - bs.rValid) / 8
+		bs.rIdx += int(n)
+		bs.rValid += n * 8
+		return
 	}
 
-	if bs.rBit == 0 {
+	loaded := false
+	for bs.rValid <= 56 && bs.rIdx < len(bs.stream) {
+		bs.rBuf |= uint64(bs.at(bs.rIdx)) << (56 - bs.rValid)
 		bs.rIdx++
-		if bs.rIdx == len(bs.stream) {
-			return false, io.EOF
-		}
+		bs.rValid += 8
+		loaded = true
+	}
 
-		bs.rBit = 8
+	// drop the bits of the last byte not written yet
+	if loaded && bs.rIdx == len(bs.stream) && bs.wBit > 0 {
+		bs.rValid -= bs.wBit
+		bs.rBuf &^= ^uint64(0) >> bs.rValid
 	}
+}
 
-	bs.rBit--
+// fill read more bytes from the source, consumed bytes are dropped
+func (bs *bstream) fill() error {
+	n := copy(bs.stream, bs.stream[bs.rIdx:])
+	bs.dropped += uint64(bs.rIdx)
+	bs.stream = bs.stream[:n]
+	bs.rIdx = 0
 
-	d := bs.stream[bs.rIdx] & (1 << bs.rBit)
-	return d != 0, nil
+	if cap(bs.stream)-n < refillSize {
+		stream := make([]byte, n, n+refillSize)
+		copy(stream, bs.stream)
+		bs.stream = stream
+	}
+
+	m, err := io.ReadAtLeast(bs.r, bs.stream[n:n+refillSize], 1)
+	bs.stream = bs.stream[:n+m]
+	return err
 }
 
-func (bs *bstream) readByte() (byte, error) {
-	if err := bs.checkReadBitSize(); err != nil {
-		return 0, err
-	}
+const refillSize = 4096
 
-	if bs.rBit == 0 {
-		bs.rIdx++
-		if bs.rIdx == len(bs.stream) {
-			return 0, io.EOF
+// ensure make sure at least nbits (<= 57) are in the read buffer
+func (bs *bstream) ensure(nbits uint8) error {
+	for bs.rValid < nbits {
+		bs.refill()
+		if bs.rValid >= nbits {
+			return nil
 		}
 
-		return bs.stream[bs.rIdx], nil
+		if bs.r == nil {
+			return io.EOF
+		}
+
+		if err := bs.fill(); err != nil {
+			return err
+		}
 	}
 
-	// we must read 8 bit data from 2 seperate bytes
-	if (bs.rIdx + 1) == len(bs.stream) {
-		return 0, io.EOF
+	return nil
+}
+
+func (bs *bstream) readBit() (bit, error) {
+	if bs.rValid == 0 {
+		if err := bs.ensure(1); err != nil {
+			return false, err
+		}
 	}
 
-	bs.rIdx++
-	return (bs.stream[bs.rIdx-1] << (8 - bs.rBit)) | (bs.stream[bs.rIdx] >> bs.rBit), nil
+	b := bs.rBuf >> 63
+	bs.rBuf <<= 1
+	bs.rValid--
+	return b == 1, nil
+}
+
+func (bs *bstream) readByte() (byte, error) {
+	b, err := bs.readBits(8)
+	return byte(b), err
 }
 
 func (bs *bstream) readBits(nbits uint) (uint64, error) {
-	var u uint64
-	for nbits >= 8 {
-		b, err := bs.readByte()
+	if nbits > uint(bs.rValid) {
+		return bs.readBitsSlow(nbits)
+	}
+
+	return bs.take(nbits), nil
+}
+
+// readBitsSlow refill the read buffer before reading, long fields are read in two parts
+func (bs *bstream) readBitsSlow(nbits uint) (uint64, error) {
+	if nbits > 56 {
+		hi, err := bs.readBits(nbits - 32)
 		if err != nil {
 			return 0, err
 		}
 
-		u = (u << 8) | uint64(b)
+		lo, err := bs.readBits(32)
+		if err != nil {
+			return 0, err
+		}
 
-		nbits -= 8
+		return hi<<32 | lo, nil
 	}
 
-	for nbits > 0 {
-		bt, err := bs.readBit()
+	if err := bs.ensure(uint8(nbits)); err != nil {
+		return 0, err
+	}
+
+	return bs.readBits(nbits)
+}
+
+// readControlBits read a control bits case of at most max bits,
+// which is a sequence of 1s terminated by a 0 or by reaching max bits
+func (bs *bstream) readControlBits(max uint8) (uint64, error) {
+	if bs.rValid < max {
+		return bs.readControlBitsSlow(max)
+	}
+
+	return bs.takeControlBits(max), nil
+}
+
+// takeControlBits read control bits of at most max bits from the read buffer, which must hold max bits
+func (bs *bstream) takeControlBits(max uint8) uint64 {
+	// count the leading 1s, capped by max
+	ones := uint8(bits.LeadingZeros64(^bs.rBuf))
+	n, ctrl := ones+1, (uint64(1)<<ones-1)<<1
+	if ones >= max {
+		n, ctrl = max, 1<<max-1
+	}
+
+	bs.rBuf <<= n
+	bs.rValid -= n
+	return ctrl
+}
+
+// take read nbits from the read buffer, which must hold nbits
+func (bs *bstream) take(nbits uint) uint64 {
+	u := bs.rBuf >> (64 - nbits)
+	bs.rBuf <<= nbits
+	bs.rValid -= uint8(nbits)
+	return u
+}
+
+func (bs *bstream) readControlBitsSlow(max uint8) (uint64, error) {
+	bs.refill()
+	if bs.rValid >= max {
+		return bs.readControlBits(max)
+	}
+
+	// close to the end of the stream
+	var ctrl uint64
+	for read := uint8(0); read < max; read++ {
+		b, err := bs.readBit()
 		if err != nil {
 			return 0, err
 		}
-		u <<= 1
-		if bt {
-			u |= 1
+
+		ctrl <<= 1
+		// get zero bit, break
+		if !b {
+			break
 		}
 
-		nbits--
+		ctrl |= 1
 	}
 
-	return u, nil
+	return ctrl, nil
 }
 
 // MarshalBinary impl encoding.BinaryMarshaler
@@ -183,9 +424,15 @@ func (bs *bstream) MarshalBinary() ([]byte, error) {
 		Writer: buf,
 	}
 
+	// the read position is kept in the layout of byte index & remaining bits
+	rIdx, rBit := int64(0), uint8(8)
+	if off := bs.readOffset(); off > 0 {
+		rIdx, rBit = int64((off-1)/8), uint8(7-(off-1)%8)
+	}
+
 	w.write(bs.wBit)
-	w.write(int64(bs.rIdx))
-	w.write(bs.rBit)
+	w.write(rIdx)
+	w.write(rBit)
 	w.write(bs.stream)
 
 	if w.err != nil {
@@ -203,18 +450,25 @@ func (bs *bstream) UnmarshalBinary(data []byte) error {
 	}
 
 	var rIdx int64
+	var rBit uint8
 
 	r.read(&bs.wBit)
 	r.read(&rIdx)
-	r.read(&bs.rBit)
+	r.read(&rBit)
 	if r.err != nil {
 		return r.err
 	}
 
-	bs.rIdx = int(rIdx)
-
 	bs.stream = make([]byte, buf.Len())
 	r.read(&bs.stream)
+	if r.err != nil {
+		return r.err
+	}
 
-	return r.err
+	if bs.wBit > 7 || (len(bs.stream) == 0 && bs.wBit > 0) || rIdx < 0 || rBit > 8 {
+		return ErrMalformedStream
+	}
+
+	bs.seek(uint64(rIdx+1)*8 - uint64(rBit))
+	return nil
 }
@@ -39,7 +39,7 @@ func TestBStreamWriteAndReadBit(t *testing.T) {
 
 	_, err := bs.readBit()
 	if err != io.EOF {
-		t.Fatalf("expected io.EOF, got %v: %d %d %d %d", err, len(bs.stream), bs.wBit, bs.rIdx, bs.rBit)
+		t.Fatalf("expected io.EOF, got %v: %d %d %d", err, len(bs.stream), bs.wBit, bs.readOffset())
 	}
 }
 
@@ -119,7 +119,7 @@ func TestBStreamWriteAndReadBits(t *testing.T) {
 	for i, s := range sets {
 		read, err := bs.readBits(s.nbits)
 		if err != nil {
-			t.Fatalf("#%d expected nil error, got %s, pos: %d, %d", i+1, err, bs.rIdx, bs.rBit)
+			t.Fatalf("#%d expected nil error, got %s, pos: %d", i+1, err, bs.readOffset())
 		}
 
 		if read != s.u {
@@ -203,3 +203,55 @@ func TestBStreamWriteAndReadDuration(t *testing.T) {
 		t.Errorf("expcted negative %d, got %d", negative, reneg)
 	}
 }
+
+func TestBStreamReadBitsWord(t *testing.T) {
+	type field struct {
+		v     uint64
+		nbits uint
+	}
+
+	fields := make([]field, 10000)
+	bs := newBStream(0)
+	for i := range fields {
+		nbits := uint(1 + rand.Intn(64))
+		v := rand.Uint64() >> (64 - nbits)
+		fields[i] = field{v, nbits}
+		bs.writeBits(v, nbits)
+	}
+
+	// a reader sharing the stream, whose last byte comes from the copy
+	shared := &bstream{
+		stream: bs.stream,
+		wBit:   bs.wBit,
+		shared: true,
+		last:   bs.stream[len(bs.stream)-1],
+	}
+
+	for _, r := range []*bstream{bs, shared} {
+		for i, f := range fields {
+			v, err := r.readBits(f.nbits)
+			if err != nil {
+				t.Fatalf("#%d read %d bits: %s", i, f.nbits, err)
+			}
+
+			if v != f.v {
+				t.Fatalf("#%d expected %x, got %x", i, f.v, v)
+			}
+		}
+
+		if _, err := r.readBit(); err != io.EOF {
+			t.Fatalf("expected io.EOF, got %v", err)
+		}
+
+		// seek back to a field in the middle
+		var off uint64
+		for _, f := range fields[:5000] {
+			off += uint64(f.nbits)
+		}
+
+		r.seek(off)
+		if v, err := r.readBits(fields[5000].nbits); err != nil || v != fields[5000].v {
+			t.Fatalf("read after seek: expected %x, got %x, %v", fields[5000].v, v, err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package chunk
+
+import "sort"
+
+// checkpoint decoder state right after the idx-th point
+type checkpoint struct {
+	idx    uint64
+	off    uint64
+	t      int64
+	tdelta int64
+	value  valueState
+}
+
+func (c *Chunk) checkpoint() {
+	if c.cpInterval == 0 || c.num%c.cpInterval != 0 {
+		return
+	}
+
+	c.cps = append(c.cps, checkpoint{
+		idx:    c.num,
+		off:    c.bs.writeOffset(),
+		t:      c.prevT,
+		tdelta: c.tdelta,
+		value:  c.value,
+	})
+}
+
+// searchCheckpoint return the last checkpoint whose timestamp is before t
+func searchCheckpoint(cps []checkpoint, t int64) (checkpoint, bool) {
+	n := sort.Search(len(cps), func(i int) bool {
+		return cps[i].t >= t
+	})
+
+	if n == 0 {
+		return checkpoint{}, false
+	}
+
+	return cps[n-1], true
+}
+
+func writeCheckpoints(w *bwriter, cps []checkpoint) {
+	w.write(uint64(len(cps)))
+	for _, cp := range cps {
+		w.write(cp.idx)
+		w.write(cp.off)
+		w.write(cp.t)
+		w.write(cp.tdelta)
+		writeValueState(w, cp.value)
+	}
+}
+
+func readCheckpoints(r *breader) []checkpoint {
+	var n uint64
+	r.read(&n)
+	if r.err != nil || n == 0 {
+		return nil
+	}
+
+	cps := make([]checkpoint, 0, 16)
+	for k := uint64(0); k < n && r.err == nil; k++ {
+		var cp checkpoint
+		r.read(&cp.idx)
+		r.read(&cp.off)
+		r.read(&cp.t)
+		r.read(&cp.tdelta)
+		cp.value = readValueState(r)
+		cps = append(cps, cp)
+	}
+
+	return cps
+}
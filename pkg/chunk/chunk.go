@@ -4,8 +4,9 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
-	"math/bits"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,25 +22,54 @@ const (
 	valueControlBits0  uint64 = 0x00
 	valueControlBits10        = 0x02
 	valueControlBits11        = 0x03
+
+	defaultCapacity = 256
 )
 
 // NewChunk return new series with second precision
-func NewChunk(t time.Time) *Chunk {
-	return newChunk(t, time.Second)
+func NewChunk(t time.Time, opts ...Option) *Chunk {
+	return newChunk(t, time.Second, precisions[time.Second], opts...)
 }
 
 // NewMilliChunk return new series with millisecond precision
-func NewMilliChunk(t time.Time) *Chunk {
-	return newChunk(t, time.Millisecond)
+func NewMilliChunk(t time.Time, opts ...Option) *Chunk {
+	return newChunk(t, time.Millisecond, precisions[time.Millisecond], opts...)
+}
+
+// NewChunkWithPrecision return new series with given precision,
+// supported precisions are time.Second, time.Millisecond, time.Microsecond and time.Nanosecond
+func NewChunkWithPrecision(t time.Time, precision time.Duration, opts ...Option) (*Chunk, error) {
+	settings, err := getPrecisionSettings(precision)
+	if err != nil {
+		return nil, err
+	}
+
+	return newChunk(t, precision, settings, opts...), nil
 }
 
-func newChunk(t time.Time, precision time.Duration) *Chunk {
+func newChunk(t time.Time, precision time.Duration, settings precisionSettings, opts ...Option) *Chunk {
 	c := &Chunk{
 		t0:                t.UnixNano() / int64(precision),
-		bs:                newBStream(10240),
-		leading:           defaultLeading,
 		precision:         precision,
-		precisionSettings: precisions[precision],
+		precisionSettings: settings,
+		capacity:          defaultCapacity,
+	}
+
+	c.value.leading = defaultLeading
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	c.bs = newBStream(c.capacity)
+	if c.acct != nil {
+		c.bs.acct = c.acct
+		atomic.AddInt64(&c.acct.chunks, 1)
+		c.acct.add(int64(cap(c.bs.stream)))
+	}
+
+	if c.vtype == TypeString {
+		c.dictIdx = map[string]uint64{}
 	}
 
 	// placeholder for point num
@@ -55,67 +85,238 @@ type Chunk struct {
 	precision time.Duration
 	precisionSettings
 
-	t0        int64
-	prevT     int64
-	tdelta    int32
-	prevVBits uint64
+	t0     int64
+	prevT  int64
+	tdelta int64
+
+	vtype   ValueType
+	value   valueState
+	dict    []string
+	dictIdx map[string]uint64
 
-	bs       *bstream
-	leading  uint8
-	trailing uint8
+	bs *bstream
 
 	finished bool
 
-	num uint64
+	num  uint64
+	minT int64
+	maxT int64
+
+	cpInterval uint64
+	cps        []checkpoint
+
+	policy OrderPolicy
+	// out-of-order lag in ticks of precision
+	lag int64
+	buf []point
+
+	// window of the block in ticks of precision, 0 for unbounded, see Window
+	window int64
+
+	// rounding of float64 values
+	lossy Lossy
+
+	// initial capacity of the stream buffer
+	capacity int
+	acct     *Accountant
+
+	summary Summary
+
+	// *view for readers, see publish
+	view atomic.Value
 }
 
-func finish(bs *bstream, precision time.Duration) {
+func finish(bs *bstream, settings precisionSettings) {
 	// write an end-of-stream record
 	bs.writeBits(dodControlBits1111, 4)
-	bs.writeBits(precisions[precision].finish.bits, precisions[precision].finish.n)
+	bs.writeBits(settings.finish.bits, settings.finish.n)
 	bs.writeBit(zero)
 }
 
-// Finish finish a stream
-func (c *Chunk) Finish() {
+// Finish finish a stream, buffered points are written before the end-of-stream record
+func (c *Chunk) Finish() error {
+	defer c.notify()
+
 	c.Lock()
+	defer c.Unlock()
 
-	if !c.finished {
-		finish(c.bs, c.precision)
-		c.finished = true
+	if c.finished {
+		return nil
 	}
 
-	c.Unlock()
+	err := c.flush(true)
+
+	finish(c.bs, c.precisionSettings)
+	c.finished = true
+	c.invalidate()
+
+	return err
 }
 
 // PushTime push time.Time and value bits
-func (c *Chunk) PushTime(t time.Time, vbits uint64) {
-	c.Push(t.UnixNano()/int64(c.precision), vbits)
+func (c *Chunk) PushTime(t time.Time, vbits uint64) error {
+	return c.Push(t.UnixNano()/int64(c.precision), vbits)
+}
+
+// PushFloat64 push timestamp and float64 value
+func (c *Chunk) PushFloat64(t int64, v float64) error {
+	return c.Push(t, math.Float64bits(v))
+}
+
+// PushInt64 push timestamp and int64 value
+func (c *Chunk) PushInt64(t int64, v int64) error {
+	return c.Push(t, uint64(v))
 }
 
-// Push push timestamp and value bits
-func (c *Chunk) Push(t int64, vbits uint64) {
+// PushBool push timestamp and bool value
+func (c *Chunk) PushBool(t int64, v bool) error {
+	var vbits uint64
+	if v {
+		vbits = 1
+	}
+
+	return c.Push(t, vbits)
+}
+
+// PushString push timestamp and string value, the value will be added into the chunk's dictionary
+func (c *Chunk) PushString(t int64, v string) error {
+	defer c.notify()
+
 	c.Lock()
 	defer c.Unlock()
 
-	c.num++
-	binary.BigEndian.PutUint64(c.bs.stream[:8], c.num)
+	if c.vtype != TypeString {
+		return ErrValueType
+	}
+
+	return c.add(point{
+		t:   t,
+		str: v,
+	})
+}
 
-	if c.prevT == 0 {
-		c.prevT = t
-		c.prevVBits = vbits
-		c.tdelta = int32(t - c.t0)
+// Push push timestamp and value bits,
+// for string chunk, vbits is the id of the value in the chunk's dictionary, use PushString instead
+func (c *Chunk) Push(t int64, vbits uint64) error {
+	defer c.notify()
+
+	c.Lock()
+	defer c.Unlock()
+
+	p := point{
+		t:     t,
+		vbits: vbits,
+	}
 
-		// with one-day block, and precision of millisecond, we need at most 28 bits
-		c.bs.writeBits(uint64(zigzag(c.tdelta)), 28)
-		c.bs.writeBits(c.prevVBits, 64)
+	if c.vtype == TypeString {
+		if vbits >= uint64(len(c.dict)) {
+			return ErrUnknownDictID
+		}
+
+		p.str = c.dict[vbits]
+	}
+
+	if c.vtype == TypeFloat64 {
+		p.vbits = c.lossy.apply(vbits)
+	}
+
+	if c.vtype == TypeBool && vbits != 0 {
+		p.vbits = 1
+	}
+
+	return c.add(p)
+}
+
+// notify call the soft limit callback of the accountant,
+// which is deferred until the lock is released
+func (c *Chunk) notify() {
+	if c.acct != nil {
+		c.acct.notify()
+	}
+}
+
+// Release return the stream buffer to the pool and stop accounting the chunk,
+// neither the chunk nor its iterators may be used afterwards
+func (c *Chunk) Release() {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.bs == nil {
 		return
 	}
 
-	// deal with delta-of-delta of timestamp
-	tdelta := int32(t - c.prevT)
-	// delta-of-delta
+	if c.bs.acct != nil {
+		atomic.AddInt64(&c.bs.acct.chunks, -1)
+	}
+
+	c.bs.release()
+	c.bs = nil
+}
+
+// encode write the point into the stream
+func (c *Chunk) encode(p point) error {
+	t := p.t
+	first := c.num == 0
+
+	tdelta := t - c.t0
+	if !first {
+		tdelta = t - c.prevT
+	}
+
 	dod := tdelta - c.tdelta
+	if err := checkTimestamp(c.precisionSettings, tdelta, dod, first); err != nil {
+		return err
+	}
+
+	vbits := p.vbits
+	if c.vtype == TypeString {
+		id, ok := c.dictIdx[p.str]
+		if !ok {
+			id = uint64(len(c.dict))
+			c.dict = append(c.dict, p.str)
+			c.dictIdx[p.str] = id
+		}
+
+		vbits = id
+	}
+
+	c.num++
+	binary.BigEndian.PutUint64(c.bs.stream[:8], headerWord(c.vtype, c.num))
+	c.summarize(t, vbits)
+
+	writeTimestamp(c.bs, c.precisionSettings, tdelta, dod, first)
+	writeValue(c.bs, c.vtype, &c.value, vbits, p.str, p.run, first)
+
+	c.prevT = t
+	c.tdelta = tdelta
+	c.checkpoint()
+	return nil
+}
+
+// checkTimestamp check if the first delta or the delta-of-delta can be encoded
+func checkTimestamp(settings precisionSettings, tdelta, dod int64, first bool) error {
+	if first {
+		if zigzag64(tdelta)>>settings.firstDeltaNBits != 0 {
+			return ErrTimestampOverflow
+		}
+
+		return nil
+	}
+
+	if !inRange(dod, settings.dod[dodControlBits1111].dodRange) {
+		return ErrTimestampOverflow
+	}
+
+	return nil
+}
+
+// writeTimestamp write the first delta, or the delta-of-delta of the following points
+func writeTimestamp(bs *bstream, settings precisionSettings, tdelta, dod int64, first bool) {
+	if first {
+		bs.writeBits(zigzag64(tdelta), settings.firstDeltaNBits)
+		return
+	}
+
 	var dodCtrlBits uint64
 
 	// in the paper of facebook's gorilla,
@@ -127,81 +328,39 @@ func (c *Chunk) Push(t int64, vbits uint64) {
 	switch {
 	case dod == 0:
 		// '0'
-		c.bs.writeBit(zero)
+		bs.writeBit(zero)
 
-	case inRange(dod, c.precisionSettings.dod[dodControlBits10].dodRange):
+	case inRange(dod, settings.dod[dodControlBits10].dodRange):
 
 		dodCtrlBits = dodControlBits10
-		c.bs.writeBits(dodControlBits10, 2)
+		bs.writeBits(dodControlBits10, 2)
 
-	case inRange(dod, c.precisionSettings.dod[dodControlBits10].dodRange):
+	case inRange(dod, settings.dod[dodControlBits110].dodRange):
 
 		dodCtrlBits = dodControlBits110
-		c.bs.writeBits(dodControlBits110, 3)
+		bs.writeBits(dodControlBits110, 3)
 
-	case inRange(dod, c.precisionSettings.dod[dodControlBits1110].dodRange):
+	case inRange(dod, settings.dod[dodControlBits1110].dodRange):
 
 		dodCtrlBits = dodControlBits1110
-		c.bs.writeBits(dodControlBits1110, 4)
+		bs.writeBits(dodControlBits1110, 4)
 
 	default:
 		// '1111' & 28 bit value
 		dodCtrlBits = dodControlBits1111
-		c.bs.writeBits(dodControlBits1111, 4)
+		bs.writeBits(dodControlBits1111, 4)
 	}
 
 	if dodCtrlBits > 0 {
-		c.bs.writeBits(uint64(zigzag(dod)), c.precisionSettings.dod[dodCtrlBits].dodNBits)
-	}
-
-	vdelta := vbits ^ c.prevVBits
-	if vdelta == 0 {
-		c.bs.writeBit(zero)
-	} else {
-		c.bs.writeBit(one)
-
-		// When XOR is non-zero, calculate the number of leading
-		// and trailing zeros in the XOR, store bit ‘1’ followed
-		// by either a) or b):
-		// 		(a) (Control bit ‘0’) If the block of meaningful bits
-		//           falls within the block of previous meaningful bits,
-		//           i.e., there are at least as many leading zeros and
-		//           as many trailing zeros as with the previous value,
-		// 		     use that information for the block position and
-		//           just store the meaningful XORed value.
-		// 		(b) (Control bit ‘1’) Store the length of the number
-		//           of leading zeros in the next 5 bits, then store the
-		//           length of the meaningful XORed value in the next
-		//           6 bits. Finally store the meaningful bits of the
-		//           XORed value.
-
-		leading := uint8(bits.LeadingZeros64(vdelta))
-		trailing := uint8(bits.TrailingZeros64(vdelta))
-
-		// leading has been set and for the meaningful bit, previous size >= current size
-		if c.leading != defaultLeading && leading >= c.leading && trailing >= c.trailing {
-			c.bs.writeBit(zero)
-			c.bs.writeBits(vdelta>>c.trailing, uint(64-c.leading-c.trailing))
-		} else {
-			c.leading, c.trailing = leading, trailing
-
-			c.bs.writeBit(one)
-			// we use 6 bit for storing leading size to support at most 63 bit leading zeros
-			c.bs.writeBits(uint64(leading), 6)
-
-			meaningfulBits := 64 - leading - trailing
-			c.bs.writeBits(uint64(meaningfulBits), 6)
-			c.bs.writeBits(vdelta>>trailing, uint(meaningfulBits))
-		}
+		bs.writeBits(zigzag64(dod), settings.dod[dodCtrlBits].dodNBits)
 	}
-
-	c.prevT = t
-	c.tdelta = tdelta
-	c.prevVBits = vbits
 }
 
 // MarshalBinary impl encoding.BinaryMarshaler
 func (c *Chunk) MarshalBinary() ([]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+
 	bsdata, err := c.bs.MarshalBinary()
 	if err != nil {
 		return nil, err
@@ -212,26 +371,134 @@ func (c *Chunk) MarshalBinary() ([]byte, error) {
 		Writer: buf,
 	}
 
-	w.write(c.precision)
 	w.write(c.t0)
 	w.write(c.prevT)
 	w.write(c.tdelta)
-	w.write(c.prevVBits)
-	w.write(c.leading)
-	w.write(c.leading)
+	writeValueState(&w, c.value)
 	w.write(c.finished)
-	w.write(c.num)
+	w.write(c.cpInterval)
+	writeCheckpoints(&w, c.cps)
+	w.write(c.policy)
+	w.write(c.lag)
+	w.write(c.window)
+	writePoints(&w, c.buf)
 	w.write(bsdata)
 
 	if w.err != nil {
-		return nil, err
+		return nil, w.err
 	}
 
-	return buf.Bytes(), nil
+	return frame(Header{
+		Precision: c.precision,
+		Type:      c.vtype,
+		Num:       c.num + uint64(len(c.buf)),
+		MinTime:   c.minT,
+		MaxTime:   c.maxT,
+		Summary:   c.fullSummary(),
+		Lossy:     c.lossy,
+	}, buf.Bytes()), nil
 }
 
-// UnmarshalBinary impl encoding.BinaryUnmarshaler
+// UnmarshalBinary impl encoding.BinaryUnmarshaler,
+// chunks written in the legacy headerless layout are accepted as well
 func (c *Chunk) UnmarshalBinary(data []byte) error {
+	var err error
+	if isFramed(data) {
+		err = c.unmarshal(data)
+	} else {
+		err = c.unmarshalLegacy(data)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.invalidate()
+	return nil
+}
+
+func (c *Chunk) unmarshal(data []byte) error {
+	h, payload, err := unframe(data)
+	if err != nil {
+		return err
+	}
+
+	c.precision = h.Precision
+	c.precisionSettings = precisions[h.Precision]
+	c.vtype = h.Type
+	c.minT = h.MinTime
+	c.maxT = h.MaxTime
+	c.lossy = h.Lossy
+
+	buf := bytes.NewBuffer(payload)
+	r := breader{
+		Reader: buf,
+	}
+
+	r.read(&c.t0)
+	r.read(&c.prevT)
+	r.read(&c.tdelta)
+	c.value = readValueState(&r)
+	r.read(&c.finished)
+	r.read(&c.cpInterval)
+	c.cps = readCheckpoints(&r)
+	r.read(&c.policy)
+	r.read(&c.lag)
+	if h.Version >= formatVersion2 {
+		r.read(&c.window)
+	}
+	c.buf = readPoints(&r)
+	if r.err != nil || !c.policy.valid() || c.window < 0 {
+		return ErrMalformedHeader
+	}
+
+	c.bs = new(bstream)
+	if err := c.bs.UnmarshalBinary(buf.Bytes()); err != nil {
+		return err
+	}
+
+	if len(c.bs.stream) < 16 {
+		return ErrMalformedHeader
+	}
+
+	_, c.num = parseHeaderWord(binary.BigEndian.Uint64(c.bs.stream[:8]))
+	if c.num+uint64(len(c.buf)) != h.Num {
+		return ErrMalformedHeader
+	}
+
+	for _, cp := range c.cps {
+		if cp.idx == 0 || cp.idx > c.num || cp.off > c.bs.writeOffset() {
+			return ErrMalformedStream
+		}
+	}
+
+	// the summary in the header includes buffered points,
+	// the dictionary of a string chunk is inline in the stream
+	if h.Version >= formatVersion2 && len(c.buf) == 0 && c.vtype != TypeString {
+		c.summary = h.Summary
+	} else {
+		minT, maxT := c.minT, c.maxT
+		it, err := c.replay()
+		if err != nil {
+			return err
+		}
+
+		c.minT, c.maxT = minT, maxT
+		c.dict = it.dict
+	}
+
+	if c.vtype == TypeString {
+		c.dictIdx = make(map[string]uint64, len(c.dict))
+		for id, v := range c.dict {
+			c.dictIdx[v] = uint64(id)
+		}
+	}
+
+	return nil
+}
+
+// unmarshalLegacy read chunk in the layout without header & checksum
+func (c *Chunk) unmarshalLegacy(data []byte) error {
 	buf := bytes.NewBuffer(data)
 	r := breader{
 		Reader: buf,
@@ -244,24 +511,30 @@ func (c *Chunk) UnmarshalBinary(data []byte) error {
 	}
 
 	c.precision = time.Duration(prec)
-	settings, ok := precisions[c.precision]
-	if !ok {
-		return fmt.Errorf("malformed precision %s", c.precision)
+	settings, err := getPrecisionSettings(c.precision)
+	if err != nil {
+		return err
 	}
 	c.precisionSettings = settings
 
+	var tdelta int32
+	var leading, trailing uint8
+
 	r.read(&c.t0)
 	r.read(&c.prevT)
-	r.read(&c.tdelta)
-	r.read(&c.prevVBits)
-	r.read(&c.leading)
-	r.read(&c.leading)
+	r.read(&tdelta)
+	r.read(&c.value.vbits)
+	r.read(&leading)
+	r.read(&trailing)
 	r.read(&c.finished)
 	r.read(&c.num)
 	if r.err != nil {
 		return r.err
 	}
 
+	c.tdelta = int64(tdelta)
+	c.vtype = TypeFloat64
+
 	bsdata := make([]byte, buf.Len())
 	r.read(&bsdata)
 	if r.err != nil {
@@ -269,33 +542,224 @@ func (c *Chunk) UnmarshalBinary(data []byte) error {
 	}
 
 	c.bs = new(bstream)
+	if err := c.bs.UnmarshalBinary(bsdata); err != nil {
+		return err
+	}
+
+	// the legacy layout lost the trailing zeros of the XOR encoder,
+	// replay the stream to rebuild the encoder state
+	it, err := c.replay()
+	if err != nil {
+		return err
+	}
+
+	c.value = it.value
+	if c.value.leading == 0 && c.value.trailing == 0 {
+		c.value.leading = defaultLeading
+	}
 
-	return c.bs.UnmarshalBinary(bsdata)
+	return nil
 }
 
-// Iter return an iterator
-func (c *Chunk) Iter() (*Iter, error) {
-	c.Lock()
+// replay decode the stream to rebuild the time range & summary,
+// return the exhausted iterator
+func (c *Chunk) replay() (*Iter, error) {
 	bs := c.bs.clone()
-	c.Unlock()
-
-	finish(bs, c.precision)
+	if !c.finished {
+		finish(bs, c.precisionSettings)
+	}
 	bs.rewind()
 
-	return bstreamIter(bs, c.precision)
+	it, err := bstreamIter(bs, c.precision)
+	if err != nil {
+		return nil, err
+	}
+
+	c.summary = Summary{}
+	for it.Next() {
+		c.track(it.t, it.read == 1)
+		c.summarize(it.t, it.value.vbits)
+	}
+
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	if it.read != c.num {
+		return nil, ErrMalformedHeader
+	}
+
+	return it, nil
+}
+
+func (c *Chunk) summarize(t int64, vbits uint64) {
+	if v, ok := c.vtype.Float64(vbits); ok {
+		c.summary.Add(t, v)
+	}
+}
+
+// Summary return the summary of numeric points, buffered points are included
+func (c *Chunk) Summary() Summary {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.fullSummary()
+}
+
+func (c *Chunk) fullSummary() Summary {
+	s := c.summary
+	for _, p := range c.buf {
+		if v, ok := c.vtype.Float64(p.vbits); ok {
+			s.Add(p.t, v)
+		}
+	}
+
+	return s
+}
+
+// track keep the min & max timestamp
+func (c *Chunk) track(t int64, first bool) {
+	if first || t < c.minT {
+		c.minT = t
+	}
+
+	if first || t > c.maxT {
+		c.maxT = t
+	}
+}
+
+// Num return the number of points, including the buffered ones
+func (c *Chunk) Num() uint64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.num + uint64(len(c.buf))
+}
+
+// MinTime return the min timestamp of points
+func (c *Chunk) MinTime() int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.minT
+}
+
+// MaxTime return the max timestamp of points
+func (c *Chunk) MaxTime() int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.maxT
+}
+
+// Precision return the precision of timestamps
+func (c *Chunk) Precision() time.Duration {
+	return c.precision
+}
+
+// Finished return true if the chunk has been finished
+func (c *Chunk) Finished() bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.finished
+}
+
+// Lossy return the rounding of float64 values
+func (c *Chunk) Lossy() Lossy {
+	return c.lossy
+}
+
+// Start return the start timestamp of the chunk
+func (c *Chunk) Start() int64 {
+	return c.t0
+}
+
+// End return the end timestamp of the chunk window, which is exclusive,
+// math.MaxInt64 if the chunk has no window
+func (c *Chunk) End() int64 {
+	if c.window == 0 {
+		return math.MaxInt64
+	}
+
+	return c.t0 + c.window
+}
+
+// Full return true if no later point can be pushed,
+// the chunk is finished or the latest point reaches the end of the window
+func (c *Chunk) Full() bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.finished {
+		return true
+	}
+
+	return c.window > 0 && c.num+uint64(len(c.buf)) > 0 && c.maxT >= c.End()-1
+}
+
+// inWindow check if the timestamp falls into the window of the chunk
+func (c *Chunk) inWindow(t int64) bool {
+	return c.window == 0 || (t >= c.t0 && t-c.t0 < c.window)
+}
+
+// Iter return an iterator, buffered points are included.
+// the iterator reads the points written so far without copying the stream,
+// the lock is only taken to copy the buffered points if there are any
+func (c *Chunk) Iter() (*Iter, error) {
+	v := c.loadView()
+
+	var tail []point
+	dict := v.dict
+	if v.nbuf > 0 {
+		c.RLock()
+		v = c.publish()
+		tail, dict = c.buffered(v.dict)
+		c.RUnlock()
+	}
+
+	bs := &bstream{
+		stream: v.stream,
+		wBit:   v.wBit,
+		shared: true,
+		last:   v.last,
+	}
+
+	// skip the header word, which is rewritten by the encoder
+	it := &Iter{
+		t0:                c.t0,
+		bs:                bs,
+		precision:         c.precision,
+		precisionSettings: c.precisionSettings,
+		vtype:             c.vtype,
+		dict:              dict,
+		start:             128,
+		cps:               v.cps,
+		encoded:           v.num,
+		num:               v.num + uint64(len(tail)),
+		tail:              tail,
+	}
+
+	bs.seek(it.start)
+	return it, nil
 }
 
 func bstreamIter(bs *bstream, precision time.Duration) (*Iter, error) {
-	precSettings, ok := precisions[precision]
-	if !ok {
-		return nil, fmt.Errorf("unsupport precision %s", precision)
+	precSettings, err := getPrecisionSettings(precision)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err := bs.readBits(64)
+	word, err := bs.readBits(64)
 	if err != nil {
 		return nil, err
 	}
 
+	vtype, num := parseHeaderWord(word)
+	if !vtype.valid() {
+		return nil, fmt.Errorf("malformed value type %s", vtype)
+	}
+
 	t0bits, err := bs.readBits(64)
 	if err != nil {
 		return nil, err
@@ -306,17 +770,30 @@ func bstreamIter(bs *bstream, precision time.Duration) (*Iter, error) {
 		bs:                bs,
 		precision:         precision,
 		precisionSettings: precSettings,
-		num:               binary.BigEndian.Uint64(bs.stream[:8]),
+		vtype:             vtype,
+		encoded:           num,
+		num:               num,
 	}
 
-	it.Stat.dod = map[uint64]int{}
-	it.Stat.vdelta = map[uint64]int{}
+	it.start = bs.readOffset()
 
 	return it, nil
 }
 
-// NewIter return new iterator with given data
+// NewIter return new iterator with given data.
+// data can be the output of Chunk.MarshalBinary, whose checksum will be verified
+// and the precision in the header will be used,
+// or a raw bit stream of the given precision
 func NewIter(data []byte, precision time.Duration) (*Iter, error) {
+	if isFramed(data) {
+		c := new(Chunk)
+		if err := c.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+
+		return c.Iter()
+	}
+
 	bs := newBStreamWithData(data)
 	return bstreamIter(bs, precision)
 }
@@ -328,31 +805,45 @@ type Iter struct {
 
 	t0     int64
 	t      int64
-	tdelta int32
-	vbits  uint64
+	tdelta int64
+
+	vtype ValueType
+	value valueState
+	dict  []string
 
-	bs       *bstream
-	leading  uint8
-	trailing uint8
+	bs *bstream
 
 	finished bool
 
-	pointStat bool
+	// bit offset of the first point
+	start uint64
+	read  uint64
+	cps   []checkpoint
 
-	Stat struct {
-		points int
-		dod    map[uint64]int
-		vdelta map[uint64]int
-	}
+	pointStat bool
+	stats     Stats
 
-	num uint64
+	// points in the stream, the end of stream is inferred from it
+	encoded uint64
+	// points buffered in the chunk, yielded after the stream
+	tail []point
+	num  uint64
 
 	err error
 }
 
-// PointStat if we stat the points
+// PointStat if we stat the points, see Stats
 func (i *Iter) PointStat(b bool) {
 	i.pointStat = b
+	if b && i.stats.DoD == nil {
+		i.stats.DoD = map[uint64]int{}
+		i.stats.Value = map[uint64]int{}
+	}
+}
+
+// Stats return the compression statistics of the points read with PointStat enabled
+func (i *Iter) Stats() Stats {
+	return i.stats.clone()
 }
 
 // Next try read next value
@@ -361,34 +852,67 @@ func (i *Iter) Next() bool {
 		return false
 	}
 
-	if i.t == 0 {
-		tdeltabits, err := i.bs.readBits(28)
-		if err != nil {
-			i.err = fmt.Errorf("read first tdelta: %s", err)
-			return false
-		}
+	if i.read >= i.encoded {
+		return i.nextBuffered()
+	}
 
-		vbits, err := i.bs.readBits(64)
-		if err != nil {
-			i.err = fmt.Errorf("read first value bits: %s", err)
-			return false
+	off := i.offset()
+	first := i.read == 0
+
+	dod, dodCtrlBits, finished, err := readTimestamp(i.bs, &i.precisionSettings, first)
+	if err != nil {
+		i.err = err
+		return false
+	}
+
+	if finished {
+		i.finished = true
+		return false
+	}
+
+	if first {
+		i.tdelta, i.t = 0, i.t0
+	}
+
+	i.tdelta += dod
+	i.t = i.t + i.tdelta
+
+	voff := i.offset()
+
+	valCtrlBits, err := i.readValue(first)
+	if err != nil {
+		i.err = err
+		return false
+	}
+
+	i.read++
+
+	if i.pointStat {
+		i.stats.add(voff-off, i.offset()-voff)
+		if !first {
+			i.stats.DoD[dodCtrlBits]++
+			i.stats.Value[valCtrlBits]++
 		}
+	}
 
-		i.tdelta = zagzig(uint32(tdeltabits))
-		i.t = i.t0 + int64(i.tdelta)
-		i.vbits = vbits
+	return true
+}
 
-		if i.pointStat {
-			i.Stat.points++
+// readTimestamp read the first delta, or the delta-of-delta & its control bits of the following points,
+// finished is set if the end-of-stream record is read
+func readTimestamp(bs *bstream, settings *precisionSettings, first bool) (int64, uint64, bool, error) {
+	if first {
+		tdeltabits, err := bs.readBits(settings.firstDeltaNBits)
+		if err != nil {
+			return 0, 0, false, fmt.Errorf("read first tdelta: %s", err)
 		}
 
-		return true
+		return zagzig64(tdeltabits), 0, false, nil
 	}
 
-	dodCtrlBits, err := readDoDControlBits(i.bs)
+	dodCtrlBits, err := readDoDControlBits(bs)
 	if err != nil {
-		i.err = fmt.Errorf("read dod control bits: %s", err)
-		return false
+		return 0, 0, false, fmt.Errorf("read dod control bits: %s", err)
 	}
 
 	var dodNBits uint
@@ -396,98 +920,258 @@ func (i *Iter) Next() bool {
 	switch dodCtrlBits {
 	case dodControlBits0:
 		// dodNBits = 0
-		// i.tdelta = i.tdelta
 
 	case dodControlBits10,
 		dodControlBits110,
 		dodControlBits1110,
 		dodControlBits1111:
-		dodNBits = i.precisionSettings.dod[dodCtrlBits].dodNBits
+		dodNBits = settings.dod[dodCtrlBits].dodNBits
 
 	default:
-		i.err = fmt.Errorf("malformed dod control bits %02x", dodCtrlBits)
-		return false
+		return 0, 0, false, fmt.Errorf("malformed dod control bits %02x", dodCtrlBits)
+	}
 
+	if dodNBits == 0 {
+		return 0, dodCtrlBits, false, nil
 	}
 
-	var dod int32
-	if dodNBits > 0 {
-		dodbits, err := i.bs.readBits(dodNBits)
-		if err != nil {
-			i.err = fmt.Errorf("read dod bits: %s", err)
-			return false
+	dodbits, err := bs.readBits(dodNBits)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("read dod bits: %s", err)
+	}
+
+	if dodCtrlBits == dodControlBits1111 && dodbits == settings.finish.bits {
+		return 0, dodCtrlBits, true, nil
+	}
+
+	return zagzig64(dodbits), dodCtrlBits, false, nil
+}
+
+// offset return the read offset for stats only
+func (i *Iter) offset() uint64 {
+	if !i.pointStat {
+		return 0
+	}
+
+	return i.bs.readOffset()
+}
+
+// NextN read at most min(len(ts), len(vs)) points into ts & vs, return the number of points read,
+// which is less than requested only at the end of the chunk or on error
+func (i *Iter) NextN(ts []int64, vs []uint64) int {
+	n := len(ts)
+	if len(vs) < n {
+		n = len(vs)
+	}
+
+	k := 0
+	for k < n {
+		// numeric points of the stream are decoded in batch,
+		// the first point, buffered points & points with stats go through Next
+		if i.err == nil && !i.finished && i.read > 0 && i.read < i.encoded && !i.pointStat &&
+			(i.vtype == TypeFloat64 || i.vtype == TypeInt64) {
+			k += i.decodeN(ts[k:n], vs[k:n])
+			continue
 		}
 
-		if dodCtrlBits == dodControlBits1111 && dodbits == i.precisionSettings.finish.bits {
-			i.finished = true
-			return false
+		if !i.Next() {
+			break
 		}
 
-		dod = zagzig(uint32(dodbits))
+		ts[k], vs[k] = i.t, i.value.vbits
+		k++
 	}
 
-	i.tdelta += dod
-	i.t = i.t + int64(i.tdelta)
+	return k
+}
 
-	valCtrlBits, err := readValueControlBits(i.bs)
-	if err != nil {
-		i.err = fmt.Errorf("read value control bits: %s", err)
-		return false
+// decodeN decode the following points of a float64 or int64 stream, at most the encoded ones.
+// the state is kept in locals & bits are taken from the read buffer directly while it holds enough of them,
+// the general readers are used otherwise
+func (i *Iter) decodeN(ts []int64, vs []uint64) int {
+	if left := i.encoded - i.read; uint64(len(ts)) > left {
+		ts = ts[:left]
 	}
 
-	var vdelta uint64
-
-	switch valCtrlBits {
-	case valueControlBits0:
-		// vdelta = 0
+	bs := i.bs
+	dods := &i.precisionSettings.dod
+	finish := i.precisionSettings.finish.bits
+	tsNBits := 4 + dods[dodControlBits1111].dodNBits
+	isFloat := i.vtype == TypeFloat64
+	t, tdelta, st := i.t, i.tdelta, i.value
+
+	var err error
+	k := 0
+	for ; k < len(ts); k++ {
+		if bs.rValid < 57 {
+			bs.refill()
+		}
 
-	case valueControlBits10:
-		meaningfulNBits := uint(64 - i.leading - i.trailing)
-		meaningful, err := i.bs.readBits(meaningfulNBits)
-		if err != nil {
-			i.err = fmt.Errorf("read meaningful value with control bits %02x: %s", valCtrlBits, err)
-			return false
+		var dod int64
+		if uint(bs.rValid) >= tsNBits {
+			if ctrl := bs.takeControlBits(4); ctrl != dodControlBits0 {
+				dodbits := bs.take(dods[ctrl].dodNBits)
+				if ctrl == dodControlBits1111 && dodbits == finish {
+					i.finished = true
+					break
+				}
+
+				dod = zagzig64(dodbits)
+			}
+		} else {
+			var finished bool
+			if dod, _, finished, err = readTimestamp(bs, &i.precisionSettings, false); err != nil || finished {
+				i.finished = finished
+				break
+			}
 		}
 
-		vdelta = meaningful << i.trailing
+		tdelta += dod
+		t += tdelta
 
-	case valueControlBits11:
-		leading, err := i.bs.readBits(6)
-		if err != nil {
-			i.err = fmt.Errorf("read leading bits: %s", err)
-			return false
+		if bs.rValid < 57 {
+			bs.refill()
 		}
 
-		meaningfulNbits, err := i.bs.readBits(6)
-		if err != nil {
-			i.err = fmt.Errorf("read meaningful bits: %s", err)
-			return false
+		if !isFloat {
+			if bs.rValid < 4 {
+				if _, err = readInt64Value(bs, &st, false); err != nil {
+					break
+				}
+			} else {
+				var vdod int64
+				if ctrl := bs.takeControlBits(4); ctrl != dodControlBits0 {
+					var dodbits uint64
+					if nbits := intDoDBuckets[ctrl].dodNBits; uint(bs.rValid) >= nbits {
+						dodbits = bs.take(nbits)
+					} else if dodbits, err = bs.readBits(nbits); err != nil {
+						break
+					}
+
+					vdod = zagzig64(dodbits)
+				}
+
+				st.vdelta += vdod
+				st.vbits = uint64(int64(st.vbits) + st.vdelta)
+			}
+		} else if bs.rValid < 2 {
+			if _, err = readFloat64Value(bs, &st, false); err != nil {
+				break
+			}
+		} else {
+			switch bs.takeControlBits(2) {
+			case valueControlBits10:
+				var meaningful uint64
+				if nbits := uint(64 - st.leading - st.trailing); uint(bs.rValid) >= nbits {
+					meaningful = bs.take(nbits)
+				} else if meaningful, err = bs.readBits(nbits); err != nil {
+					break
+				}
+
+				st.vbits ^= meaningful << st.trailing
+
+			case valueControlBits11:
+				var head, meaningful uint64
+				if bs.rValid >= 12 {
+					head = bs.take(12)
+				} else if head, err = bs.readBits(12); err != nil {
+					break
+				}
+
+				leading, nbits := head>>6, head&0x3f
+				// 64 meaningful bits overflows the 6 bits field to be 0
+				if nbits == 0 {
+					nbits = 64
+				}
+
+				if uint64(bs.rValid) >= nbits {
+					meaningful = bs.take(uint(nbits))
+				} else if meaningful, err = bs.readBits(uint(nbits)); err != nil {
+					break
+				}
+
+				st.leading, st.trailing = uint8(leading), uint8(64-leading-nbits)
+				st.vbits ^= meaningful << st.trailing
+			}
+
+			if err != nil {
+				break
+			}
 		}
 
-		meaningful, err := i.bs.readBits(uint(meaningfulNbits))
-		if err != nil {
-			i.err = fmt.Errorf("read meaningful value with control bits %02x: %s", valCtrlBits, err)
-			return false
-		}
+		ts[k], vs[k] = t, st.vbits
+	}
 
-		i.leading = uint8(leading)
-		i.trailing = uint8(64 - leading - meaningfulNbits)
-		vdelta = meaningful << i.trailing
+	if err != nil {
+		i.err = fmt.Errorf("decode point: %s", err)
+	}
 
-	default:
-		i.err = fmt.Errorf("malformed value control bits %02x", valCtrlBits)
+	i.t, i.tdelta, i.value = t, tdelta, st
+	i.read += uint64(k)
+	return k
+}
+
+func (i *Iter) nextBuffered() bool {
+	k := i.read - i.encoded
+	if k >= uint64(len(i.tail)) {
+		i.finished = true
+		return false
+	}
+
+	i.t = i.tail[k].t
+	i.value.vbits = i.tail[k].vbits
+	i.read++
+	return true
+}
+
+// SeekTo move to the first point whose timestamp >= t,
+// it is not named Seek to keep away from the io.Seeker signature
+func (i *Iter) SeekTo(t int64) bool {
+	if i.err != nil {
 		return false
 	}
 
-	i.vbits ^= vdelta
+	cp, found := searchCheckpoint(i.cps, t)
 
-	if i.pointStat {
-		i.Stat.points++
-		i.Stat.dod[dodCtrlBits]++
-		i.Stat.vdelta[valCtrlBits]++
+	// we can not move forward from current point, restore from the nearest checkpoint or the very beginning
+	if i.read == 0 || i.finished || i.t >= t || (found && cp.idx > i.read) {
+		if found {
+			i.restore(cp)
+		} else {
+			i.reset()
+		}
 	}
 
-	return true
+	for i.Next() {
+		if i.t >= t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SeekTime move to the first point whose time >= t
+func (i *Iter) SeekTime(t time.Time) bool {
+	return i.SeekTo(t.UnixNano() / int64(i.precision))
+}
+
+func (i *Iter) reset() {
+	i.bs.seek(i.start)
+	i.t = 0
+	i.tdelta = 0
+	i.value = valueState{}
+	i.read = 0
+	i.finished = false
+}
+
+func (i *Iter) restore(cp checkpoint) {
+	i.bs.seek(cp.off)
+	i.t = cp.t
+	i.tdelta = cp.tdelta
+	i.value = cp.value
+	i.read = cp.idx
+	i.finished = false
 }
 
 // Total total points
@@ -502,58 +1186,55 @@ func (i *Iter) Err() error {
 
 // Point return current point
 func (i *Iter) Point() (int64, uint64) {
-	return i.t, i.vbits
+	return i.t, i.value.vbits
 }
 
-// PointTime return point time from timestamp
-func (i *Iter) PointTime(ts int64) time.Time {
-	return time.Unix(0, ts*int64(i.precision))
+// PointFloat64 return current point with float64 value
+func (i *Iter) PointFloat64() (int64, float64) {
+	return i.t, math.Float64frombits(i.value.vbits)
 }
 
-func newTime(old time.Time, tdelta int32, precision time.Duration) time.Time {
-	return old.Add(time.Duration(tdelta) * precision)
+// PointInt64 return current point with int64 value
+func (i *Iter) PointInt64() (int64, int64) {
+	return i.t, int64(i.value.vbits)
 }
 
-func readDoDControlBits(bs *bstream) (uint64, error) {
-	var bits uint64
-	for read := 0; read < 4; read++ {
-		b, err := bs.readBit()
-		if err != nil {
-			return 0, err
-		}
-
-		bits <<= 1
-		// get zero bit, break
-		if !b {
-			break
-		}
+// PointBool return current point with bool value
+func (i *Iter) PointBool() (int64, bool) {
+	return i.t, i.value.vbits != 0
+}
 
-		bits |= 1
+// PointString return current point with string value from the dictionary
+func (i *Iter) PointString() (int64, string) {
+	if i.value.vbits < uint64(len(i.dict)) {
+		return i.t, i.dict[i.value.vbits]
 	}
 
-	return bits, nil
+	return i.t, ""
 }
 
-func readValueControlBits(bs *bstream) (uint64, error) {
-	var bits uint64
-	for read := 0; read < 2; read++ {
-		b, err := bs.readBit()
-		if err != nil {
-			return 0, err
-		}
+// Type return value type of the chunk
+func (i *Iter) Type() ValueType {
+	return i.vtype
+}
 
-		bits <<= 1
-		// get zero bit, break
-		if !b {
-			break
-		}
+// PointTime return point time from timestamp
+func (i *Iter) PointTime(ts int64) time.Time {
+	return time.Unix(0, ts*int64(i.precision))
+}
 
-		bits |= 1
-	}
+func newTime(old time.Time, tdelta int64, precision time.Duration) time.Time {
+	return old.Add(time.Duration(tdelta) * precision)
+}
 
-	return bits, nil
+func readDoDControlBits(bs *bstream) (uint64, error) {
+	return bs.readControlBits(4)
+}
+
+func readValueControlBits(bs *bstream) (uint64, error) {
+	return bs.readControlBits(2)
 }
 
-func inRange(val int32, r [2]int32) bool {
+func inRange(val int64, r [2]int64) bool {
 	return r[0] <= val && val <= r[1]
 }
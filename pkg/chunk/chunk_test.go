@@ -1,6 +1,7 @@
 package chunk
 
 import (
+	"math"
 	"math/rand"
 	"testing"
 	"time"
@@ -64,7 +65,7 @@ func TestMilliChunkAndIter(t *testing.T) {
 
 	for i := range points {
 		n := uint(4 + i%20)
-		ti = ti.Add(time.Duration(rand.Int31n(1<<n)) * time.Millisecond)
+		ti = ti.Add(time.Duration(1+rand.Int31n(1<<n)) * time.Millisecond)
 		points[i].t = ti
 		points[i].val = uint64(6 + rand.Int63n(14))
 	}
@@ -127,7 +128,7 @@ func TestMilliChunkAndIterWithMarshaling(t *testing.T) {
 
 	for i := range points {
 		n := uint(4 + i%20)
-		ti = ti.Add(time.Duration(rand.Int31n(1<<n)) * time.Millisecond)
+		ti = ti.Add(time.Duration(1+rand.Int31n(1<<n)) * time.Millisecond)
 		points[i].t = ti
 		points[i].val = uint64(6 + rand.Int63n(14))
 	}
@@ -203,7 +204,7 @@ func TestChunkAndIter(t *testing.T) {
 
 	for i := range points {
 		n := uint(4 + i%10)
-		ti = ti.Add(time.Duration(rand.Int31n(1<<n)) * time.Second)
+		ti = ti.Add(time.Duration(1+rand.Int31n(1<<n)) * time.Second)
 		points[i].t = ti
 		points[i].val = uint64(6 + rand.Int63n(14))
 	}
@@ -252,12 +253,162 @@ func TestChunkAndIter(t *testing.T) {
 	t.Logf("expected %d Bytes, got %d Bytes", len(points)*16, len(ts.bs.stream))
 }
 
+func TestChunkWithPrecision(t *testing.T) {
+	pointNum := 100000
+
+	for _, precision := range []time.Duration{time.Second, time.Millisecond, time.Microsecond, time.Nanosecond} {
+		baseT := time.Now().Truncate(24 * time.Hour)
+		ck, err := NewChunkWithPrecision(baseT, precision)
+		if err != nil {
+			t.Fatalf("new chunk with precision %s: %s", precision, err)
+		}
+
+		points := make([]time.Time, pointNum)
+		// start near the end of the day to exercise the first delta field
+		ti := baseT.Add(23 * time.Hour)
+		for i := range points {
+			var step time.Duration
+			switch i % 5 {
+			case 0:
+				step = time.Duration(rand.Int63n(int64(time.Millisecond)))
+			case 1:
+				step = time.Duration(rand.Int63n(int64(time.Second)))
+			case 2:
+				step = time.Duration(rand.Int63n(int64(time.Minute)))
+			default:
+				step = time.Duration(rand.Int63n(int64(time.Microsecond)))
+			}
+
+			ti = ti.Add(precision + step)
+			points[i] = ti
+			ck.PushTime(ti, uint64(i))
+		}
+
+		iter, err := ck.Iter()
+		if err != nil {
+			t.Fatalf("%s new iterator: %s", precision, err)
+		}
+
+		i := 0
+		for iter.Next() {
+			pt, pv := iter.Point()
+			expectedT := points[i].Truncate(precision)
+			if gotT := iter.PointTime(pt); !gotT.Equal(expectedT) {
+				t.Fatalf("%s #%d expected point time %s, got %s", precision, i+1, expectedT, gotT)
+			}
+
+			if pv != uint64(i) {
+				t.Fatalf("%s #%d expected point val %d, got %d", precision, i+1, i, pv)
+			}
+
+			i++
+		}
+
+		if err := iter.Err(); err != nil {
+			t.Fatalf("%s got iter err: %s", precision, err)
+		}
+
+		if i != pointNum {
+			t.Fatalf("%s expected %d points, got %d", precision, pointNum, i)
+		}
+	}
+
+	if _, err := NewChunkWithPrecision(time.Now(), time.Minute); err == nil {
+		t.Fatalf("expected error for unsupported precision")
+	}
+}
+
+func TestIterSeek(t *testing.T) {
+	pointNum := 10000
+
+	baseT := time.Now().Truncate(24 * time.Hour)
+	ts := make([]int64, pointNum)
+
+	for _, opts := range [][]Option{nil, {Checkpoints(64)}} {
+		ck := NewMilliChunk(baseT, opts...)
+		t0 := baseT.UnixNano() / int64(time.Millisecond)
+		tm := t0 + 3600*1000
+
+		for i := range ts {
+			tm += 1 + rand.Int63n(2000)
+			ts[i] = tm
+			ck.Push(tm, uint64(i))
+		}
+
+		data, err := ck.MarshalBinary()
+		if err != nil {
+			t.Fatalf("marshal binary %s", err)
+		}
+
+		unmarshaled := new(Chunk)
+		if err := unmarshaled.UnmarshalBinary(data); err != nil {
+			t.Fatalf("unmarshal binary %s", err)
+		}
+
+		if len(unmarshaled.cps) != len(ck.cps) {
+			t.Fatalf("expected %d checkpoints, got %d", len(ck.cps), len(unmarshaled.cps))
+		}
+
+		for _, c := range []*Chunk{ck, unmarshaled} {
+			iter, err := c.Iter()
+			if err != nil {
+				t.Fatalf("new iterator: %s", err)
+			}
+
+			for j := 0; j < 200; j++ {
+				idx := rand.Intn(pointNum)
+				target := ts[idx]
+				// somewhere between two points
+				if j%2 == 1 && idx > 0 && ts[idx]-ts[idx-1] > 1 {
+					target = ts[idx] - 1
+				}
+
+				if !iter.SeekTo(target) {
+					t.Fatalf("#%d seek %d: expected to find point, err %v", j+1, target, iter.Err())
+				}
+
+				pt, pv := iter.Point()
+				if pt != ts[idx] || pv != uint64(idx) {
+					t.Fatalf("#%d seek %d: expected point (%d, %d), got (%d, %d)", j+1, target, ts[idx], idx, pt, pv)
+				}
+
+				if idx+1 < pointNum {
+					if !iter.Next() {
+						t.Fatalf("#%d expected next point after seeking", j+1)
+					}
+
+					if pt, _ := iter.Point(); pt != ts[idx+1] {
+						t.Fatalf("#%d expected next point time %d, got %d", j+1, ts[idx+1], pt)
+					}
+				}
+			}
+
+			if !iter.SeekTo(t0) {
+				t.Fatalf("expected to seek to the first point")
+			}
+
+			if pt, _ := iter.Point(); pt != ts[0] {
+				t.Fatalf("expected first point time %d, got %d", ts[0], pt)
+			}
+
+			if iter.SeekTo(ts[pointNum-1] + 1) {
+				t.Fatalf("expected no point after the last one")
+			}
+
+			if err := iter.Err(); err != nil {
+				t.Fatalf("got iter err: %s", err)
+			}
+		}
+	}
+}
+
 func BenchmarkChunkPush(b *testing.B) {
 	baseT := time.Now()
 	tm := baseT.Add(time.Hour)
 
 	ts := NewMilliChunk(baseT.Truncate(24 * time.Hour))
 
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		tm = tm.Add(time.Duration(350+rand.Int63n(300)) * time.Millisecond)
 		ts.PushTime(tm, uint64(6+rand.Int63n(14)))
@@ -290,12 +441,45 @@ func BenchmarkChunkIterRead(b *testing.B) {
 	}
 }
 
-func BenchmarkChunkIterRead1K(b *testing.B) {
+func BenchmarkChunkIterNextN(b *testing.B) {
+	b.StopTimer()
 	baseT := time.Now()
 	tm := baseT.Add(time.Hour)
 
+	ts := NewMilliChunk(baseT.Truncate(24 * time.Hour))
+
+	for i := 0; i < b.N; i++ {
+		tm = tm.Add(time.Duration(350+rand.Int63n(300)) * time.Millisecond)
+		ts.PushTime(tm, uint64(6+rand.Int63n(14)))
+	}
+
+	iter, err := ts.Iter()
+	if err != nil {
+		b.Fatalf("get iter %s", err)
+	}
+
+	tss := make([]int64, 1024)
+	vs := make([]uint64, 1024)
+
+	b.StartTimer()
+
+	for read := 0; read < b.N; {
+		n := iter.NextN(tss, vs)
+		if n == 0 {
+			b.Fatalf("read through all points at benchmark loop %d", b.N)
+		}
+
+		read += n
+	}
+}
+
+func BenchmarkChunkIterRead1K(b *testing.B) {
+	baseT := time.Now()
+
 	for i := 0; i < b.N; i++ {
 		b.StopTimer()
+		// every chunk starts over, otherwise the first delta overflows after a few loops
+		tm := baseT.Add(time.Hour)
 		ts := NewMilliChunk(baseT.Truncate(24 * time.Hour))
 
 		for j := 0; j < 1000; j++ {
@@ -318,10 +502,11 @@ func BenchmarkChunkIterRead1K(b *testing.B) {
 
 func BenchmarkChunkIterRead10M(b *testing.B) {
 	baseT := time.Now()
-	tm := baseT.Add(time.Hour)
 
 	for i := 0; i < b.N; i++ {
 		b.StopTimer()
+		// every chunk starts over, otherwise the first delta overflows after a few loops
+		tm := baseT.Add(time.Hour)
 		ts := NewMilliChunk(baseT.Truncate(24 * time.Hour))
 
 		for j := 0; j < 10000000; j++ {
@@ -341,3 +526,184 @@ func BenchmarkChunkIterRead10M(b *testing.B) {
 		}
 	}
 }
+
+func TestIterNextN(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+	t0 := baseT.Unix()
+
+	ck := NewChunk(baseT)
+	for i := int64(0); i < 1000; i++ {
+		ck.Push(t0+i*10, uint64(i))
+	}
+
+	iter, err := ck.Iter()
+	if err != nil {
+		t.Fatalf("new iter %s", err)
+	}
+
+	ts := make([]int64, 300)
+	vs := make([]uint64, 256)
+
+	read := 0
+	for {
+		n := iter.NextN(ts, vs)
+		for k := 0; k < n; k++ {
+			if ts[k] != t0+int64(read)*10 || vs[k] != uint64(read) {
+				t.Fatalf("#%d unexpected point (%d, %d)", read, ts[k], vs[k])
+			}
+
+			read++
+		}
+
+		if n < len(vs) {
+			break
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		t.Fatalf("iter %s", err)
+	}
+
+	if read != 1000 {
+		t.Fatalf("expected 1000 points, got %d", read)
+	}
+}
+
+func TestIterNextNMatchesNext(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+
+	for _, prec := range []time.Duration{time.Second, time.Millisecond, time.Nanosecond} {
+		for _, vt := range []ValueType{TypeFloat64, TypeInt64, TypeBool} {
+			for _, finished := range []bool{false, true} {
+				ck, err := NewChunkWithPrecision(baseT, prec, Type(vt), Lag(10*prec), Checkpoints(50))
+				if err != nil {
+					t.Fatalf("new chunk %s", err)
+				}
+
+				tm := baseT.UnixNano() / int64(prec)
+				for i := 0; i < 5000; i++ {
+					// mostly regular, sometimes large gaps & wild values
+					tm += 1 + rand.Int63n(3)
+					if rand.Intn(50) == 0 {
+						tm += rand.Int63n(1 << 20)
+					}
+
+					vbits := uint64(rand.Int63n(100))
+					switch {
+					case vt == TypeBool:
+						vbits &= 1
+
+					case rand.Intn(20) == 0:
+						vbits = rand.Uint64()
+
+					case vt == TypeFloat64:
+						vbits = math.Float64bits(float64(vbits) / 4)
+					}
+
+					if err := ck.Push(tm, vbits); err != nil {
+						t.Fatalf("push %s", err)
+					}
+				}
+
+				if finished {
+					ck.Finish()
+				}
+
+				expected, err := ck.Iter()
+				if err != nil {
+					t.Fatalf("new iter %s", err)
+				}
+
+				iter, err := ck.Iter()
+				if err != nil {
+					t.Fatalf("new iter %s", err)
+				}
+
+				ts := make([]int64, 200)
+				vs := make([]uint64, 200)
+				read := 0
+				for {
+					size := 1 + rand.Intn(len(ts))
+					n := iter.NextN(ts[:size], vs)
+					for k := 0; k < n; k++ {
+						if !expected.Next() {
+							t.Fatalf("%s %s: unexpected point #%d", prec, vt, read)
+						}
+
+						if et, ev := expected.Point(); ts[k] != et || vs[k] != ev {
+							t.Fatalf("%s %s: #%d expected (%d, %d), got (%d, %d)", prec, vt, read, et, ev, ts[k], vs[k])
+						}
+
+						read++
+					}
+
+					if n < size {
+						break
+					}
+				}
+
+				if err := iter.Err(); err != nil {
+					t.Fatalf("%s %s: iter %s", prec, vt, err)
+				}
+
+				if read != 5000 || expected.Next() {
+					t.Fatalf("%s %s: expected 5000 points, got %d", prec, vt, read)
+				}
+			}
+		}
+	}
+}
+
+func TestChunkWindow(t *testing.T) {
+	baseT := time.Now().Truncate(time.Hour)
+	t0 := baseT.Unix()
+
+	ck := NewChunk(baseT, Window(time.Hour))
+	if ck.Start() != t0 || ck.End() != t0+3600 {
+		t.Fatalf("expected window [%d, %d), got [%d, %d)", t0, t0+3600, ck.Start(), ck.End())
+	}
+
+	if err := ck.Push(t0-1, 1); err != ErrOutOfWindow {
+		t.Fatalf("expected ErrOutOfWindow before the window, got %v", err)
+	}
+
+	if err := ck.Push(t0+3600, 1); err != ErrOutOfWindow {
+		t.Fatalf("expected ErrOutOfWindow after the window, got %v", err)
+	}
+
+	for ts := t0; ts < t0+3600; ts += 60 {
+		if err := ck.Push(ts, uint64(ts)); err != nil {
+			t.Fatalf("push %d %s", ts, err)
+		}
+	}
+
+	if ck.Full() {
+		t.Fatalf("expected chunk not full")
+	}
+
+	if err := ck.Push(t0+3599, 1); err != nil {
+		t.Fatalf("push the last tick %s", err)
+	}
+
+	if !ck.Full() {
+		t.Fatalf("expected chunk full")
+	}
+
+	data, err := ck.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal %s", err)
+	}
+
+	restored := new(Chunk)
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal %s", err)
+	}
+
+	if restored.End() != ck.End() || !restored.Full() {
+		t.Fatalf("expected window restored, got end %d", restored.End())
+	}
+
+	if unbounded := NewChunk(baseT); unbounded.End() != math.MaxInt64 || unbounded.Full() {
+		t.Fatalf("expected unbounded chunk")
+	}
+}
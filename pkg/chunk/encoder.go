@@ -0,0 +1,368 @@
+package chunk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+var (
+	// ErrEncoderClosed the encoder has been closed
+	ErrEncoderClosed = errors.New("encoder closed")
+)
+
+// stream binary format:
+//
+//	magic(4) | version(1) | precision(8) | bit stream
+//
+// the bit stream is laid out as the one of a chunk, with point num 0 in the header word,
+// and is always terminated by the end-of-stream record.
+const (
+	streamVersion uint8 = 1
+
+	// complete bytes are written out once the buffer grows over it
+	encoderFlushSize = 4096
+)
+
+var streamMagic = []byte("WSTM")
+
+// NewEncoder return an encoder writing a stream of points of given value type to w,
+// only a small buffer of the encoded bits is kept in memory
+func NewEncoder(w io.Writer, t time.Time, precision time.Duration, vt ValueType) (*Encoder, error) {
+	settings, err := getPrecisionSettings(precision)
+	if err != nil {
+		return nil, err
+	}
+
+	if !vt.valid() {
+		return nil, ErrValueType
+	}
+
+	buf := new(bytes.Buffer)
+	bw := bwriter{
+		Writer: buf,
+	}
+
+	bw.write(streamMagic)
+	bw.write(streamVersion)
+	bw.write(precision)
+
+	e := &Encoder{
+		w:                 w,
+		precision:         precision,
+		precisionSettings: settings,
+		vtype:             vt,
+		t0:                t.UnixNano() / int64(precision),
+		bs:                newBStreamWithData(buf.Bytes()),
+	}
+
+	e.value.leading = defaultLeading
+	if vt == TypeString {
+		e.dictIdx = map[string]uint64{}
+	}
+
+	e.bs.writeBits(headerWord(vt, 0), 64)
+	e.bs.writeBits(uint64(e.t0), 64)
+	return e, nil
+}
+
+// Encoder streaming encoder
+type Encoder struct {
+	w io.Writer
+
+	precision time.Duration
+	precisionSettings
+
+	vtype   ValueType
+	value   valueState
+	dictIdx map[string]uint64
+
+	t0     int64
+	prevT  int64
+	tdelta int64
+	num    uint64
+
+	bs *bstream
+
+	// points of the open run of a bool stream, written once the run is closed
+	run []runPoint
+
+	closed bool
+	err    error
+}
+
+// runPoint a point of a bool run not written yet
+type runPoint struct {
+	tdelta int64
+	dod    int64
+	vbits  uint64
+	first  bool
+}
+
+// Push push timestamp and value bits, points must be pushed in timestamp order
+func (e *Encoder) Push(t int64, vbits uint64) error {
+	if e.vtype == TypeString {
+		return ErrValueType
+	}
+
+	return e.encode(t, vbits, "")
+}
+
+// PushFloat64 push timestamp and float64 value
+func (e *Encoder) PushFloat64(t int64, v float64) error {
+	return e.Push(t, math.Float64bits(v))
+}
+
+// PushInt64 push timestamp and int64 value
+func (e *Encoder) PushInt64(t int64, v int64) error {
+	return e.Push(t, uint64(v))
+}
+
+// PushBool push timestamp and bool value
+func (e *Encoder) PushBool(t int64, v bool) error {
+	var vbits uint64
+	if v {
+		vbits = 1
+	}
+
+	return e.Push(t, vbits)
+}
+
+// PushString push timestamp and string value
+func (e *Encoder) PushString(t int64, v string) error {
+	if e.vtype != TypeString {
+		return ErrValueType
+	}
+
+	return e.encode(t, 0, v)
+}
+
+// encode write the point, for string streams vbits is replaced by the dictionary id of str
+func (e *Encoder) encode(t int64, vbits uint64, str string) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	if e.closed {
+		return ErrEncoderClosed
+	}
+
+	first := e.num == 0
+	if !first && t <= e.prevT {
+		if t == e.prevT {
+			return ErrDuplicatePoint
+		}
+
+		return ErrOutOfOrder
+	}
+
+	tdelta := t - e.t0
+	if !first {
+		tdelta = t - e.prevT
+	}
+
+	dod := tdelta - e.tdelta
+	if err := checkTimestamp(e.precisionSettings, tdelta, dod, first); err != nil {
+		return err
+	}
+
+	// ids are only given to values of accepted points
+	if e.vtype == TypeString {
+		id, ok := e.dictIdx[str]
+		if !ok {
+			id = uint64(len(e.dictIdx))
+			e.dictIdx[str] = id
+		}
+
+		vbits = id
+	}
+
+	if e.vtype == TypeBool {
+		if vbits != 0 {
+			vbits = 1
+		}
+
+		if len(e.run) > 0 && (e.run[0].vbits != vbits || len(e.run) == maxBoolRun) {
+			e.writeRun()
+		}
+
+		e.run = append(e.run, runPoint{
+			tdelta: tdelta,
+			dod:    dod,
+			vbits:  vbits,
+			first:  first,
+		})
+	} else {
+		writeTimestamp(e.bs, e.precisionSettings, tdelta, dod, first)
+		writeValue(e.bs, e.vtype, &e.value, vbits, str, 0, first)
+	}
+
+	e.num++
+	e.prevT = t
+	e.tdelta = tdelta
+
+	if len(e.bs.stream) > encoderFlushSize {
+		return e.Flush()
+	}
+
+	return nil
+}
+
+// writeRun write the points of the open bool run, the length is written with the first one
+func (e *Encoder) writeRun() {
+	for k, p := range e.run {
+		var run uint64
+		if k == 0 {
+			run = uint64(len(e.run))
+		}
+
+		writeTimestamp(e.bs, e.precisionSettings, p.tdelta, p.dod, p.first)
+		writeValue(e.bs, e.vtype, &e.value, p.vbits, "", run, p.first)
+	}
+
+	e.run = e.run[:0]
+}
+
+// Num return the number of points pushed
+func (e *Encoder) Num() uint64 {
+	return e.num
+}
+
+// Flush write the complete bytes of the encoded bits to the underlying writer,
+// points of the open run of a bool stream are not encoded until the run is closed
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+
+	n := len(e.bs.stream)
+	if e.bs.wBit != 0 {
+		// the last byte is still being written
+		n--
+	}
+
+	return e.write(n)
+}
+
+func (e *Encoder) write(n int) error {
+	if n <= 0 {
+		return nil
+	}
+
+	if _, err := e.w.Write(e.bs.stream[:n]); err != nil {
+		e.err = err
+		return err
+	}
+
+	e.bs.stream = e.bs.stream[:copy(e.bs.stream, e.bs.stream[n:])]
+	return nil
+}
+
+// Close write the end-of-stream record and flush all the bits, the underlying writer is not closed
+func (e *Encoder) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+
+	if e.closed {
+		return nil
+	}
+
+	e.writeRun()
+	finish(e.bs, e.precisionSettings)
+	e.closed = true
+
+	return e.write(len(e.bs.stream))
+}
+
+// NewDecoder return a decoder reading a stream written by Encoder from r
+func NewDecoder(r io.Reader) (*Decoder, error) {
+	br := breader{
+		Reader: r,
+	}
+
+	magic := make([]byte, len(streamMagic))
+	var version uint8
+	var precision time.Duration
+
+	br.read(magic)
+	br.read(&version)
+	br.read(&precision)
+	if br.err != nil || !bytes.Equal(magic, streamMagic) {
+		return nil, ErrMalformedHeader
+	}
+
+	if version != streamVersion {
+		return nil, ErrUnsupportedVersion
+	}
+
+	iter, err := bstreamIter(newBStreamReader(r), precision)
+	if err != nil {
+		return nil, err
+	}
+
+	// the number of points is unknown, read until the end-of-stream record
+	iter.encoded = math.MaxUint64
+	iter.num = 0
+
+	return &Decoder{
+		iter: iter,
+	}, nil
+}
+
+// Decoder streaming decoder
+type Decoder struct {
+	iter *Iter
+}
+
+// Next try read next value
+func (d *Decoder) Next() bool {
+	return d.iter.Next()
+}
+
+// Err return last error
+func (d *Decoder) Err() error {
+	return d.iter.Err()
+}
+
+// Point return current point
+func (d *Decoder) Point() (int64, uint64) {
+	return d.iter.Point()
+}
+
+// PointFloat64 return current point with float64 value
+func (d *Decoder) PointFloat64() (int64, float64) {
+	return d.iter.PointFloat64()
+}
+
+// PointInt64 return current point with int64 value
+func (d *Decoder) PointInt64() (int64, int64) {
+	return d.iter.PointInt64()
+}
+
+// PointBool return current point with bool value
+func (d *Decoder) PointBool() (int64, bool) {
+	return d.iter.PointBool()
+}
+
+// PointString return current point with string value
+func (d *Decoder) PointString() (int64, string) {
+	return d.iter.PointString()
+}
+
+// PointTime return point time from timestamp
+func (d *Decoder) PointTime(ts int64) time.Time {
+	return d.iter.PointTime(ts)
+}
+
+// Type return value type of the stream
+func (d *Decoder) Type() ValueType {
+	return d.iter.Type()
+}
+
+// Precision return precision of the stream
+func (d *Decoder) Precision() time.Duration {
+	return d.iter.precision
+}
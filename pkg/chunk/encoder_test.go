@@ -0,0 +1,178 @@
+package chunk
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestEncoderDecoder(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+	t0 := baseT.UnixNano() / int64(time.Millisecond)
+
+	for _, vt := range []ValueType{TypeFloat64, TypeInt64, TypeBool, TypeString} {
+		buf := new(bytes.Buffer)
+		enc, err := NewEncoder(buf, baseT, time.Millisecond, vt)
+		if err != nil {
+			t.Fatalf("%s: new encoder %s", vt, err)
+		}
+
+		type pt struct {
+			t   int64
+			v   uint64
+			str string
+		}
+
+		points := make([]pt, 100000)
+		ts := t0
+		for i := range points {
+			ts += 1 + rand.Int63n(1000)
+			p := pt{
+				t: ts,
+				v: uint64(rand.Int63n(1000)),
+			}
+
+			switch vt {
+			case TypeBool:
+				p.v &= 1
+				err = enc.Push(p.t, p.v)
+
+			case TypeString:
+				p.str = string(rune('a' + p.v%30))
+				err = enc.PushString(p.t, p.str)
+
+			default:
+				err = enc.Push(p.t, p.v)
+			}
+
+			if err != nil {
+				t.Fatalf("%s: push %s", vt, err)
+			}
+
+			if len(enc.bs.stream) > encoderFlushSize+16 {
+				t.Fatalf("%s: encoder buffers %d bytes", vt, len(enc.bs.stream))
+			}
+
+			points[i] = p
+		}
+
+		push := func(ts int64) error {
+			if vt == TypeString {
+				return enc.PushString(ts, "a")
+			}
+
+			return enc.Push(ts, 0)
+		}
+
+		if err := push(ts - 1); err != ErrOutOfOrder {
+			t.Fatalf("%s: expected ErrOutOfOrder, got %v", vt, err)
+		}
+
+		if err := enc.Close(); err != nil {
+			t.Fatalf("%s: close %s", vt, err)
+		}
+
+		if err := push(ts + 1); err != ErrEncoderClosed {
+			t.Fatalf("%s: expected ErrEncoderClosed, got %v", vt, err)
+		}
+
+		data := buf.Bytes()
+
+		dec, err := NewDecoder(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: new decoder %s", vt, err)
+		}
+
+		if dec.Type() != vt || dec.Precision() != time.Millisecond {
+			t.Fatalf("%s: unexpected decoder type %s or precision %s", vt, dec.Type(), dec.Precision())
+		}
+
+		n := 0
+		for dec.Next() {
+			p := points[n]
+			if vt == TypeString {
+				ts, str := dec.PointString()
+				if ts != p.t || str != p.str {
+					t.Fatalf("%s: #%d expected (%d, %q), got (%d, %q)", vt, n, p.t, p.str, ts, str)
+				}
+			} else if ts, v := dec.Point(); ts != p.t || v != p.v {
+				t.Fatalf("%s: #%d expected (%d, %d), got (%d, %d)", vt, n, p.t, p.v, ts, v)
+			}
+
+			n++
+		}
+
+		if err := dec.Err(); err != nil {
+			t.Fatalf("%s: decode %s", vt, err)
+		}
+
+		if n != len(points) {
+			t.Fatalf("%s: expected %d points, got %d", vt, len(points), n)
+		}
+
+		// a truncated stream must end with an error
+		dec, err = NewDecoder(bytes.NewReader(data[:len(data)/2]))
+		if err != nil {
+			t.Fatalf("%s: new decoder %s", vt, err)
+		}
+
+		for dec.Next() {
+		}
+
+		if dec.Err() == nil {
+			t.Fatalf("%s: expected error for truncated stream", vt)
+		}
+	}
+
+	if _, err := NewDecoder(bytes.NewReader([]byte("WCHK"))); err != ErrMalformedHeader {
+		t.Fatalf("expected ErrMalformedHeader, got %v", err)
+	}
+}
+
+func TestEncoderRejectedString(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+	t0 := baseT.Unix()
+
+	buf := new(bytes.Buffer)
+	enc, err := NewEncoder(buf, baseT, time.Second, TypeString)
+	if err != nil {
+		t.Fatalf("new encoder %s", err)
+	}
+
+	if err := enc.PushString(t0+10, "a"); err != nil {
+		t.Fatalf("push %s", err)
+	}
+
+	// the rejected value must not take a dictionary id
+	if err := enc.PushString(t0+5, "b"); err != ErrOutOfOrder {
+		t.Fatalf("expected ErrOutOfOrder, got %v", err)
+	}
+
+	expected := []string{"a", "c", "d", "c"}
+	for i, v := range expected[1:] {
+		if err := enc.PushString(t0+11+int64(i), v); err != nil {
+			t.Fatalf("push %s", err)
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close %s", err)
+	}
+
+	dec, err := NewDecoder(buf)
+	if err != nil {
+		t.Fatalf("new decoder %s", err)
+	}
+
+	n := 0
+	for ; dec.Next(); n++ {
+		if _, v := dec.PointString(); v != expected[n] {
+			t.Fatalf("#%d expected %q, got %q", n+1, expected[n], v)
+		}
+	}
+
+	if err := dec.Err(); err != nil || n != len(expected) {
+		t.Fatalf("expected %d points, got %d, err %v", len(expected), n, err)
+	}
+}
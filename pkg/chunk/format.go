@@ -0,0 +1,163 @@
+package chunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"time"
+)
+
+var (
+	// ErrMalformedHeader the chunk header is malformed
+	ErrMalformedHeader = errors.New("malformed chunk header")
+
+	// ErrUnsupportedVersion the chunk is written in an unknown format version
+	ErrUnsupportedVersion = errors.New("unsupported chunk format version")
+
+	// ErrChecksumMismatch the chunk is corrupted
+	ErrChecksumMismatch = errors.New("chunk checksum mismatch")
+
+	// ErrMalformedStream the bit stream or the decoder state of the chunk is malformed
+	ErrMalformedStream = errors.New("malformed chunk stream")
+)
+
+// chunk binary format:
+//
+//	magic(4) | version(1) | precision(8) | value type(1) | point num(8) |
+//	min timestamp(8) | max timestamp(8) |
+//	[summary(80) | lossy mode(1) | mantissa bits(1) | error bound(8), since v2] |
+//	payload size(4) | payload | crc32c(4)
+//
+// the checksum covers everything before it.
+// the window of the chunk is recorded in the payload since v2.
+const (
+	formatVersion1 uint8 = 1
+	formatVersion2 uint8 = 2
+
+	formatVersion = formatVersion2
+
+	headerSizeV1 = 4 + 1 + 8 + 1 + 8 + 8 + 8 + 4
+	headerSizeV2 = headerSizeV1 + summarySize + lossySize
+	summarySize  = 10 * 8
+	lossySize    = 1 + 1 + 8
+	trailerSize  = 4
+)
+
+var (
+	chunkMagic = []byte("WCHK")
+
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// Header the self-describing part of a serialized chunk
+type Header struct {
+	Version   uint8
+	Precision time.Duration
+	Type      ValueType
+	Num       uint64
+	MinTime   int64
+	MaxTime   int64
+
+	// Summary of the numeric points, empty for string chunks
+	Summary Summary
+
+	// Lossy the precision guarantee of float64 values
+	Lossy Lossy
+}
+
+// ReadHeader read & verify the header of a serialized chunk without decoding the points
+func ReadHeader(data []byte) (Header, error) {
+	h, _, err := unframe(data)
+	return h, err
+}
+
+// isFramed check if the data is written in the framed format
+func isFramed(data []byte) bool {
+	return bytes.HasPrefix(data, chunkMagic)
+}
+
+func headerSize(version uint8) int {
+	switch version {
+	case formatVersion1:
+		return headerSizeV1
+
+	default:
+		return headerSizeV2
+	}
+}
+
+func frame(h Header, payload []byte) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, headerSizeV2+len(payload)+trailerSize))
+	w := bwriter{
+		Writer: buf,
+	}
+
+	w.write(chunkMagic)
+	w.write(formatVersion)
+	w.write(h.Precision)
+	w.write(h.Type)
+	w.write(h.Num)
+	w.write(h.MinTime)
+	w.write(h.MaxTime)
+	writeSummary(&w, h.Summary)
+	writeLossy(&w, h.Lossy)
+	w.write(uint32(len(payload)))
+	w.write(payload)
+	w.write(crc32.Checksum(buf.Bytes(), crcTable))
+
+	return buf.Bytes()
+}
+
+// unframe parse the header and verify the checksum, return the payload
+func unframe(data []byte) (Header, []byte, error) {
+	var h Header
+
+	if !isFramed(data) || len(data) < len(chunkMagic)+1 {
+		return h, nil, ErrMalformedHeader
+	}
+
+	h.Version = data[len(chunkMagic)]
+	if h.Version < formatVersion1 || h.Version > formatVersion2 {
+		return h, nil, ErrUnsupportedVersion
+	}
+
+	hsize := headerSize(h.Version)
+	if len(data) < hsize+trailerSize {
+		return h, nil, ErrMalformedHeader
+	}
+
+	size := int(binary.BigEndian.Uint32(data[hsize-4:]))
+	if len(data) != hsize+size+trailerSize {
+		return h, nil, ErrMalformedHeader
+	}
+
+	sum := binary.BigEndian.Uint32(data[hsize+size:])
+	if crc32.Checksum(data[:hsize+size], crcTable) != sum {
+		return h, nil, ErrChecksumMismatch
+	}
+
+	r := breader{
+		Reader: bytes.NewReader(data[len(chunkMagic)+1 : hsize]),
+	}
+
+	r.read(&h.Precision)
+	r.read(&h.Type)
+	r.read(&h.Num)
+	r.read(&h.MinTime)
+	r.read(&h.MaxTime)
+	if h.Version >= formatVersion2 {
+		h.Summary = readSummary(&r)
+		h.Lossy = readLossy(&r)
+	}
+
+	if r.err != nil {
+		return h, nil, ErrMalformedHeader
+	}
+
+	if _, ok := precisions[h.Precision]; !ok || !h.Type.valid() || !h.Lossy.valid() {
+		return h, nil, ErrMalformedHeader
+	}
+
+	return h, data[hsize : hsize+size], nil
+}
@@ -0,0 +1,157 @@
+package chunk
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// marshalLegacy write the chunk in the layout before the framed format
+func marshalLegacy(c *Chunk) []byte {
+	bsdata, _ := c.bs.MarshalBinary()
+
+	buf := new(bytes.Buffer)
+	w := bwriter{
+		Writer: buf,
+	}
+
+	w.write(c.precision)
+	w.write(c.t0)
+	w.write(c.prevT)
+	w.write(int32(c.tdelta))
+	w.write(c.value.vbits)
+	w.write(c.value.leading)
+	w.write(c.value.leading)
+	w.write(c.finished)
+	w.write(c.num)
+	w.write(bsdata)
+
+	return buf.Bytes()
+}
+
+func newTestChunk(pointNum int) (*Chunk, []int64, []uint64) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+	ck := NewMilliChunk(baseT)
+
+	ts := make([]int64, pointNum)
+	vals := make([]uint64, pointNum)
+	tm := baseT.UnixNano()/int64(time.Millisecond) + 1000
+	for i := range ts {
+		tm += 1 + rand.Int63n(1000)
+		ts[i] = tm
+		vals[i] = rand.Uint64() >> uint(rand.Intn(64))
+		ck.Push(ts[i], vals[i])
+	}
+
+	return ck, ts, vals
+}
+
+func checkPoints(t *testing.T, iter *Iter, ts []int64, vals []uint64) {
+	i := 0
+	for iter.Next() {
+		pt, pv := iter.Point()
+		if i >= len(ts) || pt != ts[i] || pv != vals[i] {
+			t.Fatalf("#%d unexpected point (%d, %d)", i+1, pt, pv)
+		}
+
+		i++
+	}
+
+	if err := iter.Err(); err != nil {
+		t.Fatalf("got iter err: %s", err)
+	}
+
+	if i != len(ts) {
+		t.Fatalf("expected %d points, got %d", len(ts), i)
+	}
+}
+
+func TestChunkFramedFormat(t *testing.T) {
+	ck, ts, vals := newTestChunk(1000)
+
+	data, err := ck.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal binary %s", err)
+	}
+
+	if !bytes.HasPrefix(data, chunkMagic) {
+		t.Fatalf("expected magic number")
+	}
+
+	unmarshaled := new(Chunk)
+	if err := unmarshaled.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal binary %s", err)
+	}
+
+	if unmarshaled.Num() != 1000 || unmarshaled.MinTime() != ts[0] || unmarshaled.MaxTime() != ts[999] {
+		t.Fatalf("unexpected header num %d, min %d, max %d", unmarshaled.Num(), unmarshaled.MinTime(), unmarshaled.MaxTime())
+	}
+
+	// precision from the header is used
+	iter, err := NewIter(data, time.Second)
+	if err != nil {
+		t.Fatalf("new iter %s", err)
+	}
+
+	checkPoints(t, iter, ts, vals)
+
+	t.Run("Corrupted", func(t *testing.T) {
+		for i := 0; i < 200; i++ {
+			corrupted := make([]byte, len(data))
+			copy(corrupted, data)
+
+			idx := len(chunkMagic) + 1 + rand.Intn(len(data)-len(chunkMagic)-1)
+			corrupted[idx] ^= byte(1 + rand.Intn(255))
+
+			err := new(Chunk).UnmarshalBinary(corrupted)
+			if err != ErrChecksumMismatch && err != ErrMalformedHeader {
+				t.Fatalf("#%d corrupt byte %d: expected corruption error, got %v", i+1, idx, err)
+			}
+
+			if _, err := NewIter(corrupted, time.Millisecond); err == nil {
+				t.Fatalf("#%d corrupt byte %d: expected error from NewIter", i+1, idx)
+			}
+		}
+
+		if err := new(Chunk).UnmarshalBinary(data[:len(data)-1]); err != ErrMalformedHeader {
+			t.Fatalf("expected ErrMalformedHeader for truncated data, got %v", err)
+		}
+
+		bumped := make([]byte, len(data))
+		copy(bumped, data)
+		bumped[4] = formatVersion + 1
+		if err := new(Chunk).UnmarshalBinary(bumped); err != ErrUnsupportedVersion {
+			t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+		}
+	})
+}
+
+func TestChunkLegacyFormat(t *testing.T) {
+	ck, ts, vals := newTestChunk(1000)
+
+	unmarshaled := new(Chunk)
+	if err := unmarshaled.UnmarshalBinary(marshalLegacy(ck)); err != nil {
+		t.Fatalf("unmarshal legacy binary %s", err)
+	}
+
+	if unmarshaled.MinTime() != ts[0] || unmarshaled.MaxTime() != ts[999] {
+		t.Fatalf("unexpected min %d, max %d", unmarshaled.MinTime(), unmarshaled.MaxTime())
+	}
+
+	// keep pushing after reading the legacy layout
+	tm := ts[len(ts)-1]
+	for i := 0; i < 1000; i++ {
+		tm += 1 + rand.Int63n(1000)
+		ts = append(ts, tm)
+		vals = append(vals, rand.Uint64()>>uint(rand.Intn(64)))
+		unmarshaled.Push(tm, vals[len(vals)-1])
+	}
+
+	iter, err := unmarshaled.Iter()
+	if err != nil {
+		t.Fatalf("new iter %s", err)
+	}
+
+	checkPoints(t, iter, ts, vals)
+}
@@ -0,0 +1,314 @@
+package chunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+	"time"
+)
+
+var fuzzPrecisions = []time.Duration{time.Second, time.Millisecond, time.Microsecond, time.Nanosecond}
+
+type fuzzPoint struct {
+	t     int64
+	vbits uint64
+	str   string
+}
+
+// fuzzPoints turn the fuzz input into a sequence of points,
+// timestamps are varint deltas, which may be negative or overflow the dod buckets
+func fuzzPoints(t0 int64, data []byte) []fuzzPoint {
+	var points []fuzzPoint
+
+	t := t0
+	for len(data) > 0 {
+		delta, n := binary.Varint(data)
+		if n <= 0 {
+			break
+		}
+		data = data[n:]
+
+		vbits, n := binary.Uvarint(data)
+		if n <= 0 {
+			break
+		}
+		data = data[n:]
+
+		size := int(vbits % 8)
+		if size > len(data) {
+			size = len(data)
+		}
+
+		t += delta
+		points = append(points, fuzzPoint{
+			t:     t,
+			vbits: vbits,
+			str:   string(data[:size]),
+		})
+		data = data[size:]
+	}
+
+	return points
+}
+
+func fuzzPush(c *Chunk, vt ValueType, p fuzzPoint) error {
+	switch vt {
+	case TypeBool:
+		return c.PushBool(p.t, p.vbits&1 == 1)
+
+	case TypeString:
+		return c.PushString(p.t, p.str)
+
+	default:
+		return c.Push(p.t, p.vbits)
+	}
+}
+
+func fuzzCheckIter(t *testing.T, stage string, vt ValueType, iter *Iter, expected []fuzzPoint) {
+	n := 0
+	for iter.Next() {
+		if n >= len(expected) {
+			t.Fatalf("%s: more points than expected %d", stage, len(expected))
+		}
+
+		p := expected[n]
+		ts, vbits := iter.Point()
+		if vt == TypeString {
+			ts, str := iter.PointString()
+			if ts != p.t || str != p.str {
+				t.Fatalf("%s: #%d expected (%d, %q), got (%d, %q)", stage, n, p.t, p.str, ts, str)
+			}
+		} else {
+			ev := p.vbits
+			if vt == TypeBool {
+				ev &= 1
+			}
+
+			if ts != p.t || vbits != ev {
+				t.Fatalf("%s: #%d expected (%d, %x), got (%d, %x)", stage, n, p.t, ev, ts, vbits)
+			}
+		}
+
+		n++
+	}
+
+	if err := iter.Err(); err != nil {
+		t.Fatalf("%s: iter %s", stage, err)
+	}
+
+	if n != len(expected) {
+		t.Fatalf("%s: expected %d points, got %d", stage, len(expected), n)
+	}
+}
+
+func FuzzChunkRoundTrip(f *testing.F) {
+	f.Add(uint8(0), uint8(0), []byte{2, 1, 2, 3, 2, 1, 4, 5})
+	f.Add(uint8(1), uint8(1), []byte{0xd0, 0x0f, 0xff, 0xff, 0x03, 0x02, 0x07, 0x01})
+	f.Add(uint8(2), uint8(2), []byte{0x02, 0x01, 0x02, 0x00, 0x7f, 0x01})
+	f.Add(uint8(3), uint8(3), []byte{0x02, 0x03, 'a', 'b', 'c', 0x02, 0x03, 'a', 'b', 'c', 0x04, 0x01, 'x'})
+
+	// the end-of-stream sentinel value as a dod, and the largest first delta
+	for k, prec := range fuzzPrecisions {
+		settings := precisions[prec]
+		sentinel := zagzig64(settings.finish.bits)
+		first := zagzig64(1<<settings.firstDeltaNBits - 2)
+
+		var data []byte
+		data = binary.AppendVarint(data, first)
+		data = binary.AppendUvarint(data, 1)
+		data = binary.AppendVarint(data, 1)
+		data = binary.AppendUvarint(data, 2)
+		data = binary.AppendVarint(data, 1+sentinel)
+		data = binary.AppendUvarint(data, 3)
+		f.Add(uint8(k), uint8(0), data)
+	}
+
+	f.Fuzz(func(t *testing.T, prec uint8, vt uint8, data []byte) {
+		precision := fuzzPrecisions[int(prec)%len(fuzzPrecisions)]
+		vtype := ValueType(vt % 4)
+		base := time.Unix(1500000000, 0)
+
+		ck, err := NewChunkWithPrecision(base, precision, Type(vtype), Checkpoints(4))
+		if err != nil {
+			t.Fatalf("new chunk %s", err)
+		}
+
+		var expected []fuzzPoint
+		for _, p := range fuzzPoints(ck.t0, data) {
+			switch err := fuzzPush(ck, vtype, p); err {
+			case nil:
+				expected = append(expected, p)
+
+			case ErrOutOfOrder, ErrDuplicatePoint, ErrTimestampOverflow:
+
+			default:
+				t.Fatalf("push (%d, %x) %s", p.t, p.vbits, err)
+			}
+		}
+
+		iter, err := ck.Iter()
+		if err != nil {
+			t.Fatalf("new iter %s", err)
+		}
+
+		fuzzCheckIter(t, "open", vtype, iter, expected)
+
+		data, err = ck.MarshalBinary()
+		if err != nil {
+			t.Fatalf("marshal %s", err)
+		}
+
+		restored := new(Chunk)
+		if err := restored.UnmarshalBinary(data); err != nil {
+			t.Fatalf("unmarshal %s", err)
+		}
+
+		iter, err = restored.Iter()
+		if err != nil {
+			t.Fatalf("new iter %s", err)
+		}
+
+		fuzzCheckIter(t, "unmarshaled", vtype, iter, expected)
+
+		if err := ck.Finish(); err != nil {
+			t.Fatalf("finish %s", err)
+		}
+
+		data, err = ck.MarshalBinary()
+		if err != nil {
+			t.Fatalf("marshal %s", err)
+		}
+
+		iter, err = NewIter(data, precision)
+		if err != nil {
+			t.Fatalf("new iter %s", err)
+		}
+
+		fuzzCheckIter(t, "finished", vtype, iter, expected)
+
+		if len(expected) > 0 {
+			p := expected[len(expected)/2]
+			if !iter.SeekTo(p.t) {
+				t.Fatalf("seek to %d %v", p.t, iter.Err())
+			}
+
+			if ts, _ := iter.Point(); ts != p.t {
+				t.Fatalf("seek to %d, got %d", p.t, ts)
+			}
+		}
+	})
+}
+
+// fuzzSeeds return serialized chunks in every layout
+func fuzzSeeds() [][]byte {
+	var seeds [][]byte
+
+	base := time.Unix(1500000000, 0)
+	for _, vt := range []ValueType{TypeFloat64, TypeInt64, TypeBool, TypeString} {
+		for _, opts := range [][]Option{nil, {Checkpoints(2)}, {Lag(time.Minute)}} {
+			ck := NewChunk(base, append(opts, Type(vt))...)
+			for i := int64(0); i < 20; i++ {
+				fuzzPush(ck, vt, fuzzPoint{
+					t:     base.Unix() + i*i,
+					vbits: uint64(i * 7),
+					str:   string(rune('a' + i%3)),
+				})
+			}
+
+			data, _ := ck.MarshalBinary()
+			seeds = append(seeds, data)
+
+			ck.Finish()
+			data, _ = ck.MarshalBinary()
+			seeds = append(seeds, data)
+
+			// the raw bit stream
+			seeds = append(seeds, ck.bs.stream)
+
+			if vt == TypeFloat64 {
+				seeds = append(seeds, marshalLegacy(ck))
+			}
+		}
+	}
+
+	return seeds
+}
+
+// fixChecksum recompute the checksum of a framed chunk,
+// so that mutations reach the payload decoding
+func fixChecksum(data []byte) []byte {
+	if !isFramed(data) || len(data) < trailerSize {
+		return data
+	}
+
+	fixed := append([]byte(nil), data...)
+	body := fixed[:len(fixed)-trailerSize]
+	binary.BigEndian.PutUint32(fixed[len(body):], crc32.Checksum(body, crcTable))
+	return fixed
+}
+
+func fuzzDecode(t *testing.T, data []byte) {
+	c := new(Chunk)
+	if err := c.UnmarshalBinary(data); err != nil {
+		return
+	}
+
+	iter, err := c.Iter()
+	if err != nil {
+		return
+	}
+
+	for iter.Next() {
+	}
+
+	iter.SeekTo(c.MinTime())
+
+	// the restored chunk is still usable
+	if _, err := c.MarshalBinary(); err != nil {
+		t.Fatalf("marshal restored chunk %s", err)
+	}
+
+	c.Push(c.MaxTime()+1, 1)
+	c.Finish()
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	for _, seed := range fuzzSeeds() {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzDecode(t, data)
+		fuzzDecode(t, fixChecksum(data))
+	})
+}
+
+func FuzzNewIter(f *testing.F) {
+	for _, seed := range fuzzSeeds() {
+		f.Add(uint8(0), seed)
+	}
+
+	f.Fuzz(func(t *testing.T, prec uint8, data []byte) {
+		precision := fuzzPrecisions[int(prec)%len(fuzzPrecisions)]
+		for _, data := range [][]byte{data, fixChecksum(data)} {
+			iter, err := NewIter(data, precision)
+			if err != nil {
+				continue
+			}
+
+			for iter.Next() {
+			}
+
+			iter.SeekTo(0)
+		}
+
+		dec, err := NewDecoder(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		for dec.Next() {
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package chunk
+
+import (
+	"fmt"
+	"math"
+)
+
+// LossyMode how float64 values are rounded before the XOR encoding
+type LossyMode uint8
+
+const (
+	// LossyNone values are kept as they are
+	LossyNone LossyMode = iota
+	// LossyMantissa keep Lossy.MantissaBits bits of the mantissa
+	LossyMantissa
+	// LossyAbsolute the error of every value is at most Lossy.Bound
+	LossyAbsolute
+	// LossyRelative the relative error of every value is at most Lossy.Bound
+	LossyRelative
+)
+
+func (m LossyMode) String() string {
+	switch m {
+	case LossyNone:
+		return "none"
+
+	case LossyMantissa:
+		return "mantissa"
+
+	case LossyAbsolute:
+		return "absolute"
+
+	case LossyRelative:
+		return "relative"
+
+	default:
+		return fmt.Sprintf("LossyMode(%d)", uint8(m))
+	}
+}
+
+const (
+	mantissaBits = 52
+	mantissaMask = 1<<mantissaBits - 1
+	exponentMask = 0x7ff
+	exponentBias = 1023
+)
+
+// Lossy the precision guarantee of float64 values in a chunk.
+// rounded values have more trailing zeros in the mantissa,
+// which leaves less meaningful bits to the XOR encoding
+type Lossy struct {
+	Mode LossyMode
+
+	// MantissaBits bits of the mantissa kept in LossyMantissa mode, at most 52
+	MantissaBits uint8
+
+	// Bound the error bound in LossyAbsolute & LossyRelative mode
+	Bound float64
+}
+
+func (l Lossy) valid() bool {
+	switch l.Mode {
+	case LossyNone:
+		return l.MantissaBits == 0 && l.Bound == 0
+
+	case LossyMantissa:
+		return l.MantissaBits <= mantissaBits && l.Bound == 0
+
+	case LossyAbsolute, LossyRelative:
+		return l.MantissaBits == 0 && l.Bound > 0 && !math.IsInf(l.Bound, 0)
+
+	default:
+		return false
+	}
+}
+
+func (l Lossy) String() string {
+	switch l.Mode {
+	case LossyMantissa:
+		return fmt.Sprintf("%s(%d bits)", l.Mode, l.MantissaBits)
+
+	case LossyAbsolute, LossyRelative:
+		return fmt.Sprintf("%s(%g)", l.Mode, l.Bound)
+
+	default:
+		return l.Mode.String()
+	}
+}
+
+// apply round the bits of a float64 value
+func (l Lossy) apply(vbits uint64) uint64 {
+	exp := int(vbits>>mantissaBits) & exponentMask
+
+	// inf, nan & subnormal numbers are kept
+	if exp == exponentMask || exp == 0 {
+		return vbits
+	}
+
+	switch l.Mode {
+	case LossyMantissa:
+		return roundMantissa(vbits, int(l.MantissaBits))
+
+	case LossyRelative:
+		// rounding to n bits gives a relative error of at most 2^-(n+1)
+		n := int(math.Ceil(-math.Log2(l.Bound))) - 1
+		return l.roundWithin(vbits, n)
+
+	case LossyAbsolute:
+		if math.Abs(math.Float64frombits(vbits)) <= l.Bound {
+			return 0
+		}
+
+		// rounding to n bits gives an error of at most 2^(exp-n-1)
+		n := int(math.Ceil(float64(exp-exponentBias-1) - math.Log2(l.Bound)))
+		return l.roundWithin(vbits, n)
+
+	default:
+		return vbits
+	}
+}
+
+// roundWithin round the mantissa to n bits, more bits are kept if
+// the estimated n misses the bound because of floating point errors
+func (l Lossy) roundWithin(vbits uint64, n int) uint64 {
+	v := math.Float64frombits(vbits)
+	bound := l.Bound
+	if l.Mode == LossyRelative {
+		bound *= math.Abs(v)
+	}
+
+	if n < 0 {
+		n = 0
+	}
+
+	for ; n < mantissaBits; n++ {
+		rounded := roundMantissa(vbits, n)
+		if math.Abs(math.Float64frombits(rounded)-v) <= bound {
+			return rounded
+		}
+	}
+
+	return vbits
+}
+
+// roundMantissa round the mantissa to n bits, half away from zero
+func roundMantissa(vbits uint64, n int) uint64 {
+	if n >= mantissaBits {
+		return vbits
+	}
+
+	if n < 0 {
+		n = 0
+	}
+
+	drop := uint(mantissaBits - n)
+	mask := uint64(1)<<drop - 1
+
+	// a carry into the exponent is still the nearest value
+	rounded := (vbits + 1<<(drop-1)) &^ mask
+	if int(rounded>>mantissaBits)&exponentMask == exponentMask {
+		// overflow to inf, truncate instead
+		return vbits &^ mask
+	}
+
+	return rounded
+}
+
+func writeLossy(w *bwriter, l Lossy) {
+	w.write(l.Mode)
+	w.write(l.MantissaBits)
+	w.write(l.Bound)
+}
+
+func readLossy(r *breader) Lossy {
+	var l Lossy
+	r.read(&l.Mode)
+	r.read(&l.MantissaBits)
+	r.read(&l.Bound)
+	return l
+}
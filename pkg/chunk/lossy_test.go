@@ -0,0 +1,184 @@
+package chunk
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestLossyApply(t *testing.T) {
+	values := []float64{0, 1, -1, 0.1, 3.14159265358979, -2.718281828, 1e-300, 1e300, 123456.789, math.MaxFloat64, 5e-324,
+		math.Inf(1), math.Inf(-1), math.NaN()}
+	for i := 0; i < 1000; i++ {
+		values = append(values, (rand.Float64()-0.5)*math.Pow(10, float64(rand.Intn(40)-20)))
+	}
+
+	for _, l := range []Lossy{
+		{Mode: LossyMantissa, MantissaBits: 0},
+		{Mode: LossyMantissa, MantissaBits: 10},
+		{Mode: LossyMantissa, MantissaBits: 52},
+		{Mode: LossyAbsolute, Bound: 0.001},
+		{Mode: LossyAbsolute, Bound: 1e10},
+		{Mode: LossyRelative, Bound: 1e-4},
+		{Mode: LossyRelative, Bound: 0.3},
+	} {
+		if !l.valid() {
+			t.Fatalf("expected valid %s", l)
+		}
+
+		for _, v := range values {
+			got := math.Float64frombits(l.apply(math.Float64bits(v)))
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				if math.Float64bits(got) != math.Float64bits(v) {
+					t.Fatalf("%s: expected %v kept, got %v", l, v, got)
+				}
+
+				continue
+			}
+
+			if math.IsInf(got, 0) {
+				t.Fatalf("%s: %v overflows", l, v)
+			}
+
+			diff := math.Abs(got - v)
+			switch l.Mode {
+			case LossyMantissa:
+				if bound := math.Abs(v) * math.Pow(2, -float64(l.MantissaBits)-1); diff > bound {
+					t.Fatalf("%s: %v rounded to %v", l, v, got)
+				}
+
+			case LossyAbsolute:
+				if diff > l.Bound {
+					t.Fatalf("%s: %v rounded to %v", l, v, got)
+				}
+
+			case LossyRelative:
+				if diff > l.Bound*math.Abs(v) {
+					t.Fatalf("%s: %v rounded to %v", l, v, got)
+				}
+			}
+		}
+	}
+
+	for _, l := range []Lossy{
+		{Mode: LossyMantissa, MantissaBits: 53},
+		{Mode: LossyAbsolute},
+		{Mode: LossyRelative, Bound: math.NaN()},
+		{Mode: LossyRelative, Bound: math.Inf(1)},
+		{Mode: LossyRelative + 1, Bound: 1},
+	} {
+		if l.valid() {
+			t.Fatalf("expected invalid %s", l)
+		}
+	}
+}
+
+func TestLossyChunk(t *testing.T) {
+	baseT := time.Now().Truncate(time.Hour)
+	t0 := baseT.Unix()
+
+	ck := NewChunk(baseT, AbsoluteError(0.01), Checkpoints(32))
+	if l := ck.Lossy(); l.Mode != LossyAbsolute || l.Bound != 0.01 {
+		t.Fatalf("unexpected lossy mode %s", l)
+	}
+
+	expected := make([]float64, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		v := 20 + rand.NormFloat64()
+		expected = append(expected, v)
+		if err := ck.PushFloat64(t0+int64(i), v); err != nil {
+			t.Fatalf("push %s", err)
+		}
+	}
+
+	if err := ck.Finish(); err != nil {
+		t.Fatalf("finish %s", err)
+	}
+
+	data, err := ck.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal %s", err)
+	}
+
+	h, err := ReadHeader(data)
+	if err != nil {
+		t.Fatalf("read header %s", err)
+	}
+
+	if h.Lossy != ck.Lossy() {
+		t.Fatalf("expected lossy mode %s in header, got %s", ck.Lossy(), h.Lossy)
+	}
+
+	iter, err := NewIter(data, time.Second)
+	if err != nil {
+		t.Fatalf("new iter %s", err)
+	}
+
+	n := 0
+	for iter.Next() {
+		_, v := iter.PointFloat64()
+		if math.Abs(v-expected[n]) > 0.01 {
+			t.Fatalf("#%d: expected %f within 0.01, got %f", n, expected[n], v)
+		}
+
+		n++
+	}
+
+	if n != len(expected) {
+		t.Fatalf("expected %d points, got %d", len(expected), n)
+	}
+
+	// other value types are not affected
+	ick := NewChunk(baseT, Type(TypeInt64), MantissaBits(4))
+	ick.PushInt64(t0, 12345)
+	iter, _ = ick.Iter()
+	if !iter.Next() {
+		t.Fatalf("expected point")
+	}
+
+	if _, v := iter.PointInt64(); v != 12345 {
+		t.Fatalf("expected int64 value kept, got %d", v)
+	}
+}
+
+func benchmarkLossy(b *testing.B, opts ...Option) {
+	baseT := time.Now().Truncate(time.Hour)
+	t0 := baseT.Unix()
+
+	values := make([]float64, 3600)
+	for i := range values {
+		values[i] = 20 + 5*math.Sin(float64(i)/300) + rand.NormFloat64()*0.1
+	}
+
+	b.ResetTimer()
+
+	var size int
+	for i := 0; i < b.N; i++ {
+		ck := NewChunk(baseT, opts...)
+		for k, v := range values {
+			ck.PushFloat64(t0+int64(k), v)
+		}
+
+		ck.Finish()
+		size = len(ck.bs.stream)
+	}
+
+	b.ReportMetric(float64(size*8)/float64(len(values)), "bits/point")
+}
+
+func BenchmarkLossyNone(b *testing.B) {
+	benchmarkLossy(b)
+}
+
+func BenchmarkLossyMantissa12(b *testing.B) {
+	benchmarkLossy(b, MantissaBits(12))
+}
+
+func BenchmarkLossyAbsolute(b *testing.B) {
+	benchmarkLossy(b, AbsoluteError(0.01))
+}
+
+func BenchmarkLossyRelative(b *testing.B) {
+	benchmarkLossy(b, RelativeError(1e-3))
+}
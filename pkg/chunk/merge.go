@@ -0,0 +1,154 @@
+package chunk
+
+import "container/heap"
+
+// Dedup rule for points with equal timestamps from different iterators
+type Dedup uint8
+
+const (
+	// DedupFirst keep the point from the iterator which comes first
+	DedupFirst Dedup = iota
+	// DedupLast keep the point from the iterator which comes last, e.g. the latest replay
+	DedupLast
+)
+
+// NewMergeIter return an iterator yielding points of several iterators of one series in timestamp order
+func NewMergeIter(dedup Dedup, iters ...*Iter) *MergeIter {
+	return &MergeIter{
+		iters: iters,
+		dedup: dedup,
+	}
+}
+
+// MergeIter merge iterator
+type MergeIter struct {
+	iters []*Iter
+	dedup Dedup
+
+	h        mergeHeap
+	consumed []int
+	started  bool
+
+	cur *Iter
+
+	err error
+}
+
+// Next try read next value
+func (m *MergeIter) Next() bool {
+	if m.err != nil {
+		return false
+	}
+
+	if !m.started {
+		m.started = true
+		for idx := range m.iters {
+			if !m.advance(idx) {
+				return false
+			}
+		}
+	} else {
+		for _, idx := range m.consumed {
+			if !m.advance(idx) {
+				return false
+			}
+		}
+	}
+
+	m.consumed = m.consumed[:0]
+	m.cur = nil
+
+	if m.h.Len() == 0 {
+		return false
+	}
+
+	top := heap.Pop(&m.h).(mergeItem)
+	m.consumed = append(m.consumed, top.idx)
+	chosen := top.idx
+
+	for m.h.Len() > 0 && m.h[0].t == top.t {
+		dup := heap.Pop(&m.h).(mergeItem)
+		m.consumed = append(m.consumed, dup.idx)
+		if m.dedup == DedupLast {
+			chosen = dup.idx
+		}
+	}
+
+	m.cur = m.iters[chosen]
+	return true
+}
+
+// advance move the idx-th iterator forward and put it into the heap
+func (m *MergeIter) advance(idx int) bool {
+	it := m.iters[idx]
+	if it.Next() {
+		t, _ := it.Point()
+		heap.Push(&m.h, mergeItem{
+			t:   t,
+			idx: idx,
+		})
+		return true
+	}
+
+	if err := it.Err(); err != nil {
+		m.err = err
+		return false
+	}
+
+	return true
+}
+
+// Point return current point
+func (m *MergeIter) Point() (int64, uint64) {
+	if m.cur == nil {
+		return 0, 0
+	}
+
+	return m.cur.Point()
+}
+
+// Current return the iterator the current point comes from, for reading typed values
+func (m *MergeIter) Current() *Iter {
+	return m.cur
+}
+
+// Err return last error
+func (m *MergeIter) Err() error {
+	return m.err
+}
+
+type mergeItem struct {
+	t   int64
+	idx int
+}
+
+// mergeHeap min heap of iterators ordered by current timestamp, then by index
+type mergeHeap []mergeItem
+
+func (h mergeHeap) Len() int {
+	return len(h)
+}
+
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].t != h[j].t {
+		return h[i].t < h[j].t
+	}
+
+	return h[i].idx < h[j].idx
+}
+
+func (h mergeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *mergeHeap) Push(x interface{}) {
+	*h = append(*h, x.(mergeItem))
+}
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,95 @@
+package chunk
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestMergeIter(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+	t0 := baseT.Unix()
+
+	// 3 consecutive blocks and a replay overlapping the last two
+	var chunks []*Chunk
+	expected := map[int64]uint64{}
+	replayed := map[int64]uint64{}
+
+	tm := t0
+	for b := 0; b < 3; b++ {
+		ck := NewChunk(baseT)
+		for i := 0; i < 1000; i++ {
+			tm += 1 + rand.Int63n(5)
+			ck.Push(tm, uint64(b))
+			expected[tm] = uint64(b)
+		}
+
+		chunks = append(chunks, ck)
+	}
+
+	replay := NewChunk(baseT)
+	for ts := t0 + 3000; ts < tm+100; ts += 1 + rand.Int63n(3) {
+		replay.Push(ts, 100)
+		replayed[ts] = 100
+	}
+
+	chunks = append(chunks, replay)
+
+	for _, dedup := range []Dedup{DedupFirst, DedupLast} {
+		iters := make([]*Iter, len(chunks))
+		for i, ck := range chunks {
+			iter, err := ck.Iter()
+			if err != nil {
+				t.Fatalf("new iter %s", err)
+			}
+
+			iters[i] = iter
+		}
+
+		merged := map[int64]uint64{}
+		for ts, v := range expected {
+			merged[ts] = v
+		}
+
+		for ts, v := range replayed {
+			if _, ok := merged[ts]; ok && dedup == DedupFirst {
+				continue
+			}
+
+			merged[ts] = v
+		}
+
+		mi := NewMergeIter(dedup, iters...)
+		count := 0
+		last := int64(-1)
+		for mi.Next() {
+			pt, pv := mi.Point()
+			if pt <= last {
+				t.Fatalf("dedup %d: expected increasing timestamps, got %d after %d", dedup, pt, last)
+			}
+
+			if v, ok := merged[pt]; !ok || v != pv {
+				t.Fatalf("dedup %d: unexpected point (%d, %d), expected value %d", dedup, pt, pv, v)
+			}
+
+			if cur := mi.Current(); cur == nil {
+				t.Fatalf("expected current iterator")
+			}
+
+			last = pt
+			count++
+		}
+
+		if err := mi.Err(); err != nil {
+			t.Fatalf("got merge iter err: %s", err)
+		}
+
+		if count != len(merged) {
+			t.Fatalf("dedup %d: expected %d points, got %d", dedup, len(merged), count)
+		}
+	}
+
+	if NewMergeIter(DedupFirst).Next() {
+		t.Fatalf("expected no point from empty merge iterator")
+	}
+}
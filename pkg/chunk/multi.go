@@ -0,0 +1,451 @@
+package chunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrColumnCount the number of values does not match the columns of the chunk
+	ErrColumnCount = errors.New("mismatched column count")
+
+	// ErrUnknownColumn the column is not in the chunk
+	ErrUnknownColumn = errors.New("unknown column")
+)
+
+// multi chunk binary format:
+//
+//	magic(4) | version(1) | precision(8) | columns(4) | point num(8) |
+//	t0(8) | prev timestamp(8) | tdelta(8) | finished(1) |
+//	timestamp stream size(4) | timestamp stream |
+//	columns * (value state | value stream size(4) | value stream) | crc32c(4)
+const multiFormatVersion uint8 = 1
+
+var multiMagic = []byte("WMCK")
+
+// NewMultiChunk return a chunk of float64 columns sharing one timestamp stream,
+// supported precisions are the same as NewChunkWithPrecision
+func NewMultiChunk(t time.Time, precision time.Duration, columns int) (*MultiChunk, error) {
+	settings, err := getPrecisionSettings(precision)
+	if err != nil {
+		return nil, err
+	}
+
+	if columns <= 0 {
+		return nil, ErrColumnCount
+	}
+
+	c := &MultiChunk{
+		precision:         precision,
+		precisionSettings: settings,
+		t0:                t.UnixNano() / int64(precision),
+		ts:                newBStream(1024),
+		cols:              make([]column, columns),
+	}
+
+	for k := range c.cols {
+		c.cols[k] = newColumn(newBStream(1024))
+	}
+
+	return c, nil
+}
+
+// MultiChunk wide chunk, the timestamps are delta-of-delta encoded once,
+// and every column is XOR encoded in its own stream
+type MultiChunk struct {
+	sync.RWMutex
+
+	precision time.Duration
+	precisionSettings
+
+	t0     int64
+	prevT  int64
+	tdelta int64
+
+	ts   *bstream
+	cols []column
+
+	finished bool
+	num      uint64
+}
+
+type column struct {
+	bs    *bstream
+	value valueState
+}
+
+func newColumn(bs *bstream) column {
+	col := column{
+		bs: bs,
+	}
+
+	col.value.leading = defaultLeading
+	return col
+}
+
+// Columns return the number of columns
+func (c *MultiChunk) Columns() int {
+	return len(c.cols)
+}
+
+// Num return the number of points
+func (c *MultiChunk) Num() uint64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.num
+}
+
+// MinTime return the min timestamp of points
+func (c *MultiChunk) MinTime() int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.num == 0 {
+		return 0
+	}
+
+	return c.t0 + c.firstDelta()
+}
+
+// MaxTime return the max timestamp of points
+func (c *MultiChunk) MaxTime() int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.prevT
+}
+
+// firstDelta read the first delta back from the timestamp stream
+func (c *MultiChunk) firstDelta() int64 {
+	bs := &bstream{
+		stream: c.ts.stream,
+		wBit:   c.ts.wBit,
+	}
+
+	tdelta, _, _, _ := readTimestamp(bs, &c.precisionSettings, true)
+	return tdelta
+}
+
+// Finish finish the chunk
+func (c *MultiChunk) Finish() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.finished {
+		finish(c.ts, c.precisionSettings)
+		c.finished = true
+	}
+
+	return nil
+}
+
+// PushFloat64 push timestamp and a float64 value for every column
+func (c *MultiChunk) PushFloat64(t int64, vs []float64) error {
+	vbits := make([]uint64, len(vs))
+	for k, v := range vs {
+		vbits[k] = math.Float64bits(v)
+	}
+
+	return c.Push(t, vbits)
+}
+
+// Push push timestamp and value bits for every column,
+// timestamps must be increasing
+func (c *MultiChunk) Push(t int64, vbits []uint64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.finished {
+		return ErrChunkFinished
+	}
+
+	if len(vbits) != len(c.cols) {
+		return ErrColumnCount
+	}
+
+	first := c.num == 0
+	tdelta := t - c.t0
+	if !first {
+		if t == c.prevT {
+			return ErrDuplicatePoint
+		}
+
+		if t < c.prevT {
+			return ErrOutOfOrder
+		}
+
+		tdelta = t - c.prevT
+	}
+
+	dod := tdelta - c.tdelta
+	if err := checkTimestamp(c.precisionSettings, tdelta, dod, first); err != nil {
+		return err
+	}
+
+	writeTimestamp(c.ts, c.precisionSettings, tdelta, dod, first)
+	for k := range c.cols {
+		col := &c.cols[k]
+		writeValue(col.bs, TypeFloat64, &col.value, vbits[k], "", 0, first)
+	}
+
+	c.num++
+	c.prevT = t
+	c.tdelta = tdelta
+	return nil
+}
+
+// Iter return an iterator over the given columns, all columns if none is given.
+// columns not projected are not decoded, the streams are read in place without copying
+func (c *MultiChunk) Iter(cols ...int) (*MultiIter, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if len(cols) == 0 {
+		cols = make([]int, len(c.cols))
+		for k := range cols {
+			cols[k] = k
+		}
+	}
+
+	it := &MultiIter{
+		precision:         c.precision,
+		precisionSettings: c.precisionSettings,
+		t0:                c.t0,
+		ts:                c.ts.reader(),
+		cols:              make([]column, len(cols)),
+		idx:               append([]int(nil), cols...),
+		vals:              make([]uint64, len(cols)),
+		num:               c.num,
+	}
+
+	for k, idx := range cols {
+		if idx < 0 || idx >= len(c.cols) {
+			return nil, ErrUnknownColumn
+		}
+
+		it.cols[k] = newColumn(c.cols[idx].bs.reader())
+	}
+
+	return it, nil
+}
+
+// MultiIter iterator over projected columns of a MultiChunk
+type MultiIter struct {
+	precision time.Duration
+	precisionSettings
+
+	t0     int64
+	t      int64
+	tdelta int64
+
+	ts   *bstream
+	cols []column
+	idx  []int
+	vals []uint64
+
+	num  uint64
+	read uint64
+
+	err error
+}
+
+// Next try read next point
+func (i *MultiIter) Next() bool {
+	if i.err != nil || i.read >= i.num {
+		return false
+	}
+
+	first := i.read == 0
+	delta, _, finished, err := readTimestamp(i.ts, &i.precisionSettings, first)
+	if err != nil {
+		i.err = err
+		return false
+	}
+
+	if finished {
+		i.err = ErrMalformedStream
+		return false
+	}
+
+	if first {
+		i.tdelta = delta
+		i.t = i.t0 + delta
+	} else {
+		i.tdelta += delta
+		i.t += i.tdelta
+	}
+
+	for k := range i.cols {
+		col := &i.cols[k]
+		if _, err := readFloat64Value(col.bs, &col.value, first); err != nil {
+			i.err = err
+			return false
+		}
+
+		i.vals[k] = col.value.vbits
+	}
+
+	i.read++
+	return true
+}
+
+// Columns return the projected columns
+func (i *MultiIter) Columns() []int {
+	return i.idx
+}
+
+// Time return the timestamp of current point
+func (i *MultiIter) Time() int64 {
+	return i.t
+}
+
+// Values return value bits of the projected columns, which are overwritten by Next
+func (i *MultiIter) Values() []uint64 {
+	return i.vals
+}
+
+// Float64 return the float64 value of the k-th projected column
+func (i *MultiIter) Float64(k int) float64 {
+	return math.Float64frombits(i.vals[k])
+}
+
+// PointTime return point time from timestamp
+func (i *MultiIter) PointTime(ts int64) time.Time {
+	return time.Unix(0, ts*int64(i.precision))
+}
+
+// Err return last error
+func (i *MultiIter) Err() error {
+	return i.err
+}
+
+// MarshalBinary impl encoding.BinaryMarshaler
+func (c *MultiChunk) MarshalBinary() ([]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	buf := new(bytes.Buffer)
+	w := bwriter{
+		Writer: buf,
+	}
+
+	w.write(multiMagic)
+	w.write(multiFormatVersion)
+	w.write(c.precision)
+	w.write(uint32(len(c.cols)))
+	w.write(c.num)
+	w.write(c.t0)
+	w.write(c.prevT)
+	w.write(c.tdelta)
+	w.write(c.finished)
+	writeStream(&w, c.ts)
+	for _, col := range c.cols {
+		writeValueState(&w, col.value)
+		writeStream(&w, col.bs)
+	}
+
+	w.write(crc32.Checksum(buf.Bytes(), crcTable))
+
+	if w.err != nil {
+		return nil, w.err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary impl encoding.BinaryUnmarshaler
+func (c *MultiChunk) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, multiMagic) || len(data) < len(multiMagic)+1+trailerSize {
+		return ErrMalformedHeader
+	}
+
+	if data[len(multiMagic)] != multiFormatVersion {
+		return ErrUnsupportedVersion
+	}
+
+	body := data[:len(data)-trailerSize]
+	if crc32.Checksum(body, crcTable) != binary.BigEndian.Uint32(data[len(body):]) {
+		return ErrChecksumMismatch
+	}
+
+	buf := bytes.NewReader(body[len(multiMagic)+1:])
+	r := breader{
+		Reader: buf,
+	}
+
+	var ncols uint32
+	r.read(&c.precision)
+	r.read(&ncols)
+	r.read(&c.num)
+	r.read(&c.t0)
+	r.read(&c.prevT)
+	r.read(&c.tdelta)
+	r.read(&c.finished)
+	if r.err != nil {
+		return ErrMalformedHeader
+	}
+
+	settings, err := getPrecisionSettings(c.precision)
+	if err != nil || ncols == 0 {
+		return ErrMalformedHeader
+	}
+	c.precisionSettings = settings
+
+	if c.ts, err = readStream(&r); err != nil {
+		return err
+	}
+
+	c.cols = make([]column, 0, 16)
+	for k := uint32(0); k < ncols; k++ {
+		st := readValueState(&r)
+		bs, err := readStream(&r)
+		if err != nil {
+			return err
+		}
+
+		c.cols = append(c.cols, column{
+			bs:    bs,
+			value: st,
+		})
+	}
+
+	if buf.Len() != 0 {
+		return ErrMalformedHeader
+	}
+
+	return nil
+}
+
+func writeStream(w *bwriter, bs *bstream) {
+	data, err := bs.MarshalBinary()
+	if err != nil {
+		w.err = err
+		return
+	}
+
+	w.write(uint32(len(data)))
+	w.write(data)
+}
+
+func readStream(r *breader) (*bstream, error) {
+	var size uint32
+	r.read(&size)
+
+	data := r.readBytes(uint64(size))
+	if r.err != nil {
+		return nil, ErrMalformedHeader
+	}
+
+	bs := new(bstream)
+	if err := bs.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return bs, nil
+}
@@ -0,0 +1,207 @@
+package chunk
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMultiChunk(t *testing.T) {
+	baseT := time.Now().Truncate(time.Hour)
+	t0 := baseT.Unix()
+
+	if _, err := NewMultiChunk(baseT, time.Second, 0); err != ErrColumnCount {
+		t.Fatalf("expected ErrColumnCount, got %v", err)
+	}
+
+	const ncols = 8
+	ck, err := NewMultiChunk(baseT, time.Second, ncols)
+	if err != nil {
+		t.Fatalf("new multi chunk %s", err)
+	}
+
+	var times []int64
+	var rows [][]float64
+
+	tm := t0
+	for i := 0; i < 2000; i++ {
+		tm += 9 + rand.Int63n(3)
+		row := make([]float64, ncols)
+		for k := range row {
+			row[k] = math.Round(float64(k*10)+rand.NormFloat64()*100) / 100
+		}
+
+		if err := ck.PushFloat64(tm, row); err != nil {
+			t.Fatalf("push %s", err)
+		}
+
+		times = append(times, tm)
+		rows = append(rows, row)
+	}
+
+	if err := ck.PushFloat64(tm+1, make([]float64, ncols-1)); err != ErrColumnCount {
+		t.Fatalf("expected ErrColumnCount, got %v", err)
+	}
+
+	if err := ck.PushFloat64(tm, make([]float64, ncols)); err != ErrDuplicatePoint {
+		t.Fatalf("expected ErrDuplicatePoint, got %v", err)
+	}
+
+	if err := ck.PushFloat64(tm-1, make([]float64, ncols)); err != ErrOutOfOrder {
+		t.Fatalf("expected ErrOutOfOrder, got %v", err)
+	}
+
+	if ck.Num() != uint64(len(times)) || ck.MinTime() != times[0] || ck.MaxTime() != tm {
+		t.Fatalf("unexpected num %d or time range [%d, %d]", ck.Num(), ck.MinTime(), ck.MaxTime())
+	}
+
+	check := func(stage string, c *MultiChunk, cols ...int) {
+		iter, err := c.Iter(cols...)
+		if err != nil {
+			t.Fatalf("%s: new iter %s", stage, err)
+		}
+
+		if len(cols) == 0 {
+			cols = iter.Columns()
+		}
+
+		n := 0
+		for iter.Next() {
+			if iter.Time() != times[n] {
+				t.Fatalf("%s: #%d expected timestamp %d, got %d", stage, n, times[n], iter.Time())
+			}
+
+			for k, col := range cols {
+				if v := iter.Float64(k); v != rows[n][col] {
+					t.Fatalf("%s: #%d column %d expected %f, got %f", stage, n, col, rows[n][col], v)
+				}
+			}
+
+			n++
+		}
+
+		if err := iter.Err(); err != nil {
+			t.Fatalf("%s: iter %s", stage, err)
+		}
+
+		if n != len(times) {
+			t.Fatalf("%s: expected %d points, got %d", stage, len(times), n)
+		}
+	}
+
+	check("all", ck)
+	check("projected", ck, 6, 1)
+
+	if _, err := ck.Iter(ncols); err != ErrUnknownColumn {
+		t.Fatalf("expected ErrUnknownColumn, got %v", err)
+	}
+
+	for _, finished := range []bool{false, true} {
+		if finished {
+			if err := ck.Finish(); err != nil {
+				t.Fatalf("finish %s", err)
+			}
+		}
+
+		data, err := ck.MarshalBinary()
+		if err != nil {
+			t.Fatalf("marshal %s", err)
+		}
+
+		restored := new(MultiChunk)
+		if err := restored.UnmarshalBinary(data); err != nil {
+			t.Fatalf("unmarshal %s", err)
+		}
+
+		check("restored", restored, 3)
+
+		data[len(data)/2] ^= 0xff
+		if err := new(MultiChunk).UnmarshalBinary(data); err != ErrChecksumMismatch {
+			t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+		}
+	}
+
+	if err := ck.PushFloat64(tm+1, make([]float64, ncols)); err != ErrChunkFinished {
+		t.Fatalf("expected ErrChunkFinished, got %v", err)
+	}
+}
+
+func TestMultiChunkConcurrentIter(t *testing.T) {
+	baseT := time.Now().Truncate(time.Hour)
+	t0 := baseT.Unix()
+
+	ck, err := NewMultiChunk(baseT, time.Second, 2)
+	if err != nil {
+		t.Fatalf("new multi chunk %s", err)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				// the iterator reads the points pushed before it is created
+				iter, err := ck.Iter(1)
+				if err != nil {
+					t.Errorf("new iter %s", err)
+					return
+				}
+
+				n := int64(0)
+				for iter.Next() {
+					if iter.Time() != t0+n || iter.Float64(0) != float64(n) {
+						t.Errorf("#%d unexpected point (%d, %f)", n, iter.Time(), iter.Float64(0))
+						return
+					}
+
+					n++
+				}
+
+				if err := iter.Err(); err != nil {
+					t.Errorf("iter %s", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := int64(0); i < 5000; i++ {
+		if err := ck.PushFloat64(t0+i, []float64{-float64(i), float64(i)}); err != nil {
+			t.Fatalf("push %s", err)
+		}
+	}
+
+	ck.Finish()
+	close(done)
+	wg.Wait()
+}
+
+func BenchmarkMultiChunkPush(b *testing.B) {
+	baseT := time.Now().Truncate(time.Hour)
+	tm := baseT.Unix()
+
+	ck, _ := NewMultiChunk(baseT, time.Second, 16)
+	row := make([]float64, 16)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tm += 10
+		for k := range row {
+			row[k] = float64(k) + rand.Float64()
+		}
+
+		ck.PushFloat64(tm, row)
+	}
+}
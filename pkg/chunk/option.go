@@ -0,0 +1,102 @@
+package chunk
+
+import "time"
+
+// Option chunk option
+type Option func(c *Chunk)
+
+// Checkpoints record a checkpoint every n points, which makes Iter.SeekTo & Iter.SeekTime faster
+func Checkpoints(n int) Option {
+	return func(c *Chunk) {
+		if n > 0 {
+			c.cpInterval = uint64(n)
+		}
+	}
+}
+
+// Type set value type of the chunk, default TypeFloat64
+func Type(vt ValueType) Option {
+	return func(c *Chunk) {
+		if vt.valid() {
+			c.vtype = vt
+		}
+	}
+}
+
+// Order set the policy for out-of-order & duplicate points, default PolicyReject
+func Order(p OrderPolicy) Option {
+	return func(c *Chunk) {
+		if p.valid() {
+			c.policy = p
+		}
+	}
+}
+
+// Lag buffer points in memory, so that points arriving at most d later
+// than the latest one can still be merged in order
+func Lag(d time.Duration) Option {
+	return func(c *Chunk) {
+		if d > 0 {
+			c.lag = int64(d / c.precision)
+		}
+	}
+}
+
+// Window limit the chunk to the block [t, t+d), where t is the start time of the chunk,
+// points outside the block are rejected with ErrOutOfWindow
+func Window(d time.Duration) Option {
+	return func(c *Chunk) {
+		if d > 0 {
+			c.window = int64(d / c.precision)
+		}
+	}
+}
+
+// MantissaBits keep n bits of the mantissa of float64 values, the rest are rounded off
+func MantissaBits(n int) Option {
+	return lossy(Lossy{
+		Mode:         LossyMantissa,
+		MantissaBits: uint8(n),
+	}, n >= 0 && n <= mantissaBits)
+}
+
+// AbsoluteError round float64 values, with an error of at most e
+func AbsoluteError(e float64) Option {
+	return lossy(Lossy{
+		Mode:  LossyAbsolute,
+		Bound: e,
+	}, true)
+}
+
+// RelativeError round float64 values, with a relative error of at most e
+func RelativeError(e float64) Option {
+	return lossy(Lossy{
+		Mode:  LossyRelative,
+		Bound: e,
+	}, true)
+}
+
+func lossy(l Lossy, ok bool) Option {
+	return func(c *Chunk) {
+		if ok && l.valid() {
+			c.lossy = l
+		}
+	}
+}
+
+// Capacity set the initial capacity of the stream buffer in bytes, default 256.
+// the buffer grows through size-classed pools, see Chunk.Release
+func Capacity(n int) Option {
+	return func(c *Chunk) {
+		if n > 0 {
+			c.capacity = n
+		}
+	}
+}
+
+// Account track the bytes held by the chunk with the accountant, until the chunk is released
+func Account(a *Accountant) Option {
+	return func(c *Chunk) {
+		c.acct = a
+	}
+}
@@ -0,0 +1,248 @@
+package chunk
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	// ErrOutOfOrder the point is older than the latest point written into the chunk
+	ErrOutOfOrder = errors.New("out-of-order point")
+
+	// ErrDuplicatePoint the point has the same timestamp with an existing one
+	ErrDuplicatePoint = errors.New("duplicate point")
+
+	// ErrTimestampOverflow the timestamp is too far away from the previous one to be encoded
+	ErrTimestampOverflow = errors.New("timestamp overflow")
+
+	// ErrChunkFinished the chunk has been finished
+	ErrChunkFinished = errors.New("chunk finished")
+
+	// ErrUnknownDictID the value id is not in the dictionary of a string chunk
+	ErrUnknownDictID = errors.New("unknown dictionary id")
+
+	// ErrValueType the value does not match the value type of the chunk
+	ErrValueType = errors.New("mismatched value type")
+
+	// ErrOutOfWindow the point is outside the window of the chunk
+	ErrOutOfWindow = errors.New("point out of chunk window")
+)
+
+// OrderPolicy how to deal with out-of-order & duplicate points
+type OrderPolicy uint8
+
+const (
+	// PolicyReject reject the point with ErrOutOfOrder or ErrDuplicatePoint
+	PolicyReject OrderPolicy = iota
+	// PolicyDrop drop the point silently
+	PolicyDrop
+	// PolicyLastWriteWins the duplicate point overwrites the existing one if it has not been encoded yet,
+	// out-of-order points are rejected
+	PolicyLastWriteWins
+)
+
+func (p OrderPolicy) valid() bool {
+	return p <= PolicyLastWriteWins
+}
+
+// point a point not encoded yet
+type point struct {
+	t     int64
+	vbits uint64
+	str   string
+
+	// length of the bool run the point starts, set right before it is encoded
+	run uint64
+}
+
+// add accept or reject a point according to the order policy,
+// points are buffered if an out-of-order lag is set or the policy is last-write-wins,
+// as well as the open run of a bool chunk
+func (c *Chunk) add(p point) error {
+	defer c.invalidate()
+
+	if c.finished {
+		return ErrChunkFinished
+	}
+
+	if !c.inWindow(p.t) {
+		return ErrOutOfWindow
+	}
+
+	if c.num > 0 && p.t <= c.prevT {
+		return c.reject(p.t == c.prevT)
+	}
+
+	first := c.num == 0 && len(c.buf) == 0
+
+	inOrder := c.lag == 0 && c.policy != PolicyLastWriteWins
+	if inOrder && c.vtype != TypeBool {
+		if err := c.encode(p); err != nil {
+			return err
+		}
+
+		c.track(p.t, first)
+		return nil
+	}
+
+	if inOrder && len(c.buf) > 0 && p.t < c.buf[len(c.buf)-1].t {
+		return c.reject(false)
+	}
+
+	idx := sort.Search(len(c.buf), func(i int) bool {
+		return c.buf[i].t >= p.t
+	})
+
+	if idx < len(c.buf) && c.buf[idx].t == p.t {
+		if c.policy == PolicyLastWriteWins {
+			c.buf[idx] = p
+			return nil
+		}
+
+		return c.reject(true)
+	}
+
+	if err := c.checkBuffered(p.t, idx); err != nil {
+		return err
+	}
+
+	c.buf = append(c.buf, point{})
+	copy(c.buf[idx+1:], c.buf[idx:])
+	c.buf[idx] = p
+	c.track(p.t, first)
+
+	return c.flush(false)
+}
+
+// checkBuffered check if the buffered points can still be encoded with a point at t inserted at idx,
+// so that the error is returned to the caller instead of a later flush
+func (c *Chunk) checkBuffered(t int64, idx int) error {
+	first := c.num == 0
+	prevT, tdelta := c.prevT, c.tdelta
+	if first {
+		prevT = c.t0
+	}
+
+	for k := 0; k <= len(c.buf); k++ {
+		pt := t
+		if k < idx {
+			pt = c.buf[k].t
+		} else if k > idx {
+			pt = c.buf[k-1].t
+		}
+
+		delta := pt - prevT
+		if err := checkTimestamp(c.precisionSettings, delta, delta-tdelta, first); err != nil {
+			return err
+		}
+
+		prevT, tdelta, first = pt, delta, false
+	}
+
+	return nil
+}
+
+func (c *Chunk) reject(duplicate bool) error {
+	if c.policy == PolicyDrop {
+		return nil
+	}
+
+	if duplicate {
+		return ErrDuplicatePoint
+	}
+
+	return ErrOutOfOrder
+}
+
+// flush encode buffered points which are old enough, or all of them,
+// points of a bool chunk are encoded once their run is closed
+func (c *Chunk) flush(all bool) error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+
+	latest := c.buf[len(c.buf)-1].t
+
+	n := len(c.buf)
+	if !all {
+		for k, p := range c.buf {
+			// with last-write-wins, the latest point is kept for overwriting
+			if p.t > latest-c.lag || (c.lag == 0 && p.t == latest) {
+				n = k
+				break
+			}
+		}
+	}
+
+	if c.vtype == TypeBool {
+		n = c.closeRuns(n, all)
+	}
+
+	var err error
+	for _, p := range c.buf[:n] {
+		if e := c.encode(p); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	c.buf = c.buf[:copy(c.buf, c.buf[n:])]
+	return err
+}
+
+// closeRuns set the length on the first point of each bool run within the first n buffered points,
+// return the number of points in the runs which can be encoded.
+// the last run is kept open unless all points are flushed or it reaches maxBoolRun,
+// as later points may still join it
+func (c *Chunk) closeRuns(n int, all bool) int {
+	k := 0
+	for k < n {
+		m := 1
+		for k+m < len(c.buf) && m < maxBoolRun && c.buf[k+m].vbits == c.buf[k].vbits {
+			m++
+		}
+
+		if k+m > n || (k+m == len(c.buf) && m < maxBoolRun && !all) {
+			break
+		}
+
+		c.buf[k].run = uint64(m)
+		k += m
+	}
+
+	return k
+}
+
+func writePoints(w *bwriter, points []point) {
+	w.write(uint64(len(points)))
+	for _, p := range points {
+		w.write(p.t)
+		w.write(p.vbits)
+		w.write(uint32(len(p.str)))
+		w.write([]byte(p.str))
+	}
+}
+
+func readPoints(r *breader) []point {
+	var n uint64
+	r.read(&n)
+	if r.err != nil || n == 0 {
+		return nil
+	}
+
+	points := make([]point, 0, 16)
+	for k := uint64(0); k < n && r.err == nil; k++ {
+		var p point
+		var size uint32
+		r.read(&p.t)
+		r.read(&p.vbits)
+		r.read(&size)
+		if r.err != nil {
+			break
+		}
+
+		p.str = string(r.readBytes(uint64(size)))
+		points = append(points, p)
+	}
+
+	return points
+}
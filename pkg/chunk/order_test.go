@@ -0,0 +1,232 @@
+package chunk
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestChunkOrderPolicy(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+	t0 := baseT.Unix()
+
+	t.Run("Reject", func(t *testing.T) {
+		ck := NewChunk(baseT)
+		if err := ck.Push(t0+10, 1); err != nil {
+			t.Fatalf("push %s", err)
+		}
+
+		if err := ck.Push(t0+10, 2); err != ErrDuplicatePoint {
+			t.Fatalf("expected ErrDuplicatePoint, got %v", err)
+		}
+
+		if err := ck.Push(t0+5, 3); err != ErrOutOfOrder {
+			t.Fatalf("expected ErrOutOfOrder, got %v", err)
+		}
+
+		if err := ck.Push(t0+1<<40, 4); err != ErrTimestampOverflow {
+			t.Fatalf("expected ErrTimestampOverflow, got %v", err)
+		}
+
+		if err := ck.Finish(); err != nil {
+			t.Fatalf("finish %s", err)
+		}
+
+		if err := ck.Push(t0+20, 5); err != ErrChunkFinished {
+			t.Fatalf("expected ErrChunkFinished, got %v", err)
+		}
+
+		if ck.Num() != 1 {
+			t.Fatalf("expected 1 point, got %d", ck.Num())
+		}
+	})
+
+	t.Run("Drop", func(t *testing.T) {
+		ck := NewChunk(baseT, Order(PolicyDrop))
+		for _, ts := range []int64{10, 10, 5, 11} {
+			if err := ck.Push(t0+ts, uint64(ts)); err != nil {
+				t.Fatalf("push %s", err)
+			}
+		}
+
+		if ck.Num() != 2 {
+			t.Fatalf("expected 2 points, got %d", ck.Num())
+		}
+	})
+
+	t.Run("LastWriteWins", func(t *testing.T) {
+		ck := NewChunk(baseT, Order(PolicyLastWriteWins))
+		for i, ts := range []int64{10, 10, 11, 11, 12} {
+			if err := ck.Push(t0+ts, uint64(i)); err != nil {
+				t.Fatalf("#%d push %s", i+1, err)
+			}
+		}
+
+		// the duplicate of an encoded point can not be overwritten
+		if err := ck.Push(t0+11, 9); err != ErrDuplicatePoint {
+			t.Fatalf("expected ErrDuplicatePoint, got %v", err)
+		}
+
+		iter, err := ck.Iter()
+		if err != nil {
+			t.Fatalf("new iter %s", err)
+		}
+
+		checkPoints(t, iter, []int64{t0 + 10, t0 + 11, t0 + 12}, []uint64{1, 3, 4})
+	})
+
+	t.Run("Lag", func(t *testing.T) {
+		pointNum := 10000
+		lag := 30
+
+		ts := make([]int64, pointNum)
+		vals := make([]uint64, pointNum)
+		for i := range ts {
+			ts[i] = t0 + int64(i)*2
+			vals[i] = uint64(i)
+		}
+
+		// shuffle points inside the lag window
+		order := make([]int, pointNum)
+		for i := range order {
+			order[i] = i
+		}
+
+		for i := 0; i < pointNum; i += lag / 2 {
+			end := i + lag/2
+			if end > pointNum {
+				end = pointNum
+			}
+
+			rand.Shuffle(end-i, func(a, b int) {
+				order[i+a], order[i+b] = order[i+b], order[i+a]
+			})
+		}
+
+		ck := NewChunk(baseT, Lag(time.Duration(lag)*time.Second), Checkpoints(100))
+		for _, idx := range order {
+			if err := ck.Push(ts[idx], vals[idx]); err != nil {
+				t.Fatalf("push point #%d: %s", idx, err)
+			}
+		}
+
+		if len(ck.buf) == 0 {
+			t.Fatalf("expected buffered points")
+		}
+
+		// buffered points survive marshaling
+		data, err := ck.MarshalBinary()
+		if err != nil {
+			t.Fatalf("marshal binary %s", err)
+		}
+
+		unmarshaled := new(Chunk)
+		if err := unmarshaled.UnmarshalBinary(data); err != nil {
+			t.Fatalf("unmarshal binary %s", err)
+		}
+
+		for _, c := range []*Chunk{ck, unmarshaled} {
+			if c.Num() != uint64(pointNum) {
+				t.Fatalf("expected %d points, got %d", pointNum, c.Num())
+			}
+
+			iter, err := c.Iter()
+			if err != nil {
+				t.Fatalf("new iter %s", err)
+			}
+
+			checkPoints(t, iter, ts, vals)
+		}
+
+		if err := ck.Push(ts[0]+1, 0); err != ErrOutOfOrder {
+			t.Fatalf("expected ErrOutOfOrder for point older than the lag, got %v", err)
+		}
+
+		if err := ck.Finish(); err != nil {
+			t.Fatalf("finish %s", err)
+		}
+
+		if len(ck.buf) != 0 {
+			t.Fatalf("expected all points flushed")
+		}
+
+		iter, err := NewIter(ck.bs.stream, time.Second)
+		if err != nil {
+			t.Fatalf("new iter %s", err)
+		}
+
+		checkPoints(t, iter, ts, vals)
+	})
+
+	t.Run("LagOverflow", func(t *testing.T) {
+		for _, opt := range []Option{Lag(time.Minute), Order(PolicyLastWriteWins)} {
+			ck := NewChunk(baseT, opt)
+			if err := ck.Push(t0+10, 1); err != nil {
+				t.Fatalf("push %s", err)
+			}
+
+			// rejected when pushed, instead of failing a later push or finish
+			if err := ck.Push(t0+1<<40, 2); err != ErrTimestampOverflow {
+				t.Fatalf("expected ErrTimestampOverflow, got %v", err)
+			}
+
+			for _, ts := range []int64{20, 15} {
+				if err := ck.Push(t0+ts, uint64(ts)); err != nil {
+					t.Fatalf("push %s", err)
+				}
+			}
+
+			if err := ck.Finish(); err != nil {
+				t.Fatalf("finish %s", err)
+			}
+
+			iter, err := ck.Iter()
+			if err != nil {
+				t.Fatalf("new iter %s", err)
+			}
+
+			checkPoints(t, iter, []int64{t0 + 10, t0 + 15, t0 + 20}, []uint64{1, 15, 20})
+		}
+	})
+
+	t.Run("LagString", func(t *testing.T) {
+		ck := NewChunk(baseT, Type(TypeString), Lag(time.Minute))
+		pushed := []struct {
+			t int64
+			v string
+		}{
+			{10, "b"},
+			{5, "a"},
+			{20, "c"},
+			{7, "b"},
+		}
+
+		for _, p := range pushed {
+			if err := ck.PushString(t0+p.t, p.v); err != nil {
+				t.Fatalf("push %s", err)
+			}
+		}
+
+		iter, err := ck.Iter()
+		if err != nil {
+			t.Fatalf("new iter %s", err)
+		}
+
+		expected := []string{"a", "b", "b", "c"}
+		i := 0
+		for iter.Next() {
+			if _, v := iter.PointString(); v != expected[i] {
+				t.Fatalf("#%d expected %q, got %q", i+1, expected[i], v)
+			}
+			i++
+		}
+
+		if i != len(expected) {
+			t.Fatalf("expected %d points, got %d", len(expected), i)
+		}
+
+		if len(ck.dict) != 0 {
+			t.Fatalf("expected dictionary untouched by iterating buffered points, got %v", ck.dict)
+		}
+	})
+}
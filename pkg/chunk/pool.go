@@ -0,0 +1,131 @@
+package chunk
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// stream buffers are pooled in size classes of 64B, 256B, ... 1MB,
+// larger buffers are allocated directly
+const (
+	minClassShift  = 6
+	classShiftStep = 2
+	numClasses     = 8
+)
+
+var bufferPools [numClasses]sync.Pool
+
+// sizeClass return the index of the smallest class holding n bytes, -1 if n is too large
+func sizeClass(n int) int {
+	for k := 0; k < numClasses; k++ {
+		if n <= classSize(k) {
+			return k
+		}
+	}
+
+	return -1
+}
+
+func classSize(k int) int {
+	return 1 << (minClassShift + k*classShiftStep)
+}
+
+// getBuffer return an empty buffer with a capacity of at least n bytes
+func getBuffer(n int) []byte {
+	if n <= 0 {
+		return nil
+	}
+
+	k := sizeClass(n)
+	if k < 0 {
+		return make([]byte, 0, n)
+	}
+
+	if p, ok := bufferPools[k].Get().(*[]byte); ok {
+		return (*p)[:0]
+	}
+
+	return make([]byte, 0, classSize(k))
+}
+
+// putBuffer return the buffer to the pool, buffers not allocated by getBuffer are dropped
+func putBuffer(buf []byte) {
+	k := sizeClass(cap(buf))
+	if k < 0 || cap(buf) != classSize(k) {
+		return
+	}
+
+	buf = buf[:0]
+	bufferPools[k].Put(&buf)
+}
+
+// NewAccountant return an accountant with a soft limit in bytes, 0 for no limit.
+// onLimit is called with the bytes in use when the usage grows beyond the limit,
+// it is called again only after the usage drops below the limit
+func NewAccountant(limit int64, onLimit func(used int64)) *Accountant {
+	return &Accountant{
+		limit:   limit,
+		onLimit: onLimit,
+	}
+}
+
+// Accountant track the bytes held by chunks created with the Account option,
+// until they are released
+type Accountant struct {
+	used    int64
+	chunks  int64
+	limit   int64
+	onLimit func(used int64)
+
+	// accountBelow, accountExceeded or accountPending
+	state int32
+}
+
+const (
+	accountBelow int32 = iota
+	// the limit is exceeded and the callback has been called
+	accountExceeded
+	// the limit is exceeded and the callback is to be called
+	accountPending
+)
+
+// Used return the bytes in use
+func (a *Accountant) Used() int64 {
+	return atomic.LoadInt64(&a.used)
+}
+
+// Chunks return the number of chunks not released yet
+func (a *Accountant) Chunks() int64 {
+	return atomic.LoadInt64(&a.chunks)
+}
+
+// Limit return the soft limit
+func (a *Accountant) Limit() int64 {
+	return a.limit
+}
+
+func (a *Accountant) add(delta int64) {
+	used := atomic.AddInt64(&a.used, delta)
+	if a.limit <= 0 {
+		return
+	}
+
+	if used > a.limit {
+		atomic.CompareAndSwapInt32(&a.state, accountBelow, accountPending)
+		return
+	}
+
+	atomic.StoreInt32(&a.state, accountBelow)
+}
+
+// notify call the callback if the limit has been exceeded since the last call,
+// it must be called without holding any chunk lock, so that the callback is free to flush chunks
+func (a *Accountant) notify() {
+	if !atomic.CompareAndSwapInt32(&a.state, accountPending, accountExceeded) {
+		return
+	}
+
+	if a.onLimit != nil {
+		a.onLimit(a.Used())
+	}
+}
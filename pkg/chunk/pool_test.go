@@ -0,0 +1,120 @@
+package chunk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBufferPool(t *testing.T) {
+	for _, c := range []struct {
+		n   int
+		cap int
+	}{
+		{0, 0},
+		{1, 64},
+		{64, 64},
+		{65, 256},
+		{10240, 16384},
+		{1 << 20, 1 << 20},
+		{1<<20 + 1, 1<<20 + 1},
+	} {
+		buf := getBuffer(c.n)
+		if len(buf) != 0 || cap(buf) != c.cap {
+			t.Fatalf("get buffer of %d: expected cap %d, got len %d cap %d", c.n, c.cap, len(buf), cap(buf))
+		}
+
+		putBuffer(buf)
+	}
+
+	// buffers of other sizes are not pooled
+	putBuffer(make([]byte, 100))
+	if buf := getBuffer(100); cap(buf) != 256 {
+		t.Fatalf("expected pooled buffer of 256 bytes, got %d", cap(buf))
+	}
+
+	bs := newBStream(1)
+	for i := 0; i < 1000; i++ {
+		bs.writeBits(uint64(i), 13)
+	}
+
+	bs.rewind()
+	for i := 0; i < 1000; i++ {
+		if v, err := bs.readBits(13); err != nil || v != uint64(i) {
+			t.Fatalf("#%d: expected %d, got %d, %v", i, i, v, err)
+		}
+	}
+}
+
+func TestAccountant(t *testing.T) {
+	baseT := time.Now().Truncate(time.Hour)
+	t0 := baseT.Unix()
+
+	var chunks []*Chunk
+	var calls []int64
+
+	acct := NewAccountant(4096, func(used int64) {
+		calls = append(calls, used)
+
+		// flush the oldest chunk, which may be the one being pushed into
+		oldest := chunks[0]
+		chunks = chunks[1:]
+		oldest.Finish()
+		oldest.Release()
+	})
+
+	for k := 0; k < 8; k++ {
+		chunks = append(chunks, NewChunk(baseT, Capacity(64), Account(acct)))
+	}
+
+	if acct.Used() != 8*64 || acct.Chunks() != 8 {
+		t.Fatalf("expected 8 chunks of 64 bytes, got %d bytes, %d chunks", acct.Used(), acct.Chunks())
+	}
+
+	for i := int64(0); len(calls) == 0; i++ {
+		for _, c := range chunks {
+			if err := c.PushFloat64(t0+i, float64(i)*1.1); err != nil {
+				t.Fatalf("push %s", err)
+			}
+
+			if len(calls) > 0 {
+				break
+			}
+		}
+	}
+
+	if len(calls) != 1 || calls[0] <= acct.Limit() {
+		t.Fatalf("expected one callback beyond the limit, got %v", calls)
+	}
+
+	if acct.Chunks() != 7 {
+		t.Fatalf("expected 7 chunks after flushing, got %d", acct.Chunks())
+	}
+
+	// the limit is exceeded again after dropping below it
+	for i := int64(10000); len(calls) == 1; i++ {
+		if err := chunks[0].PushFloat64(t0+i, float64(i)*1.1); err != nil {
+			t.Fatalf("push %s", err)
+		}
+	}
+
+	if acct.Chunks() != 6 {
+		t.Fatalf("expected 6 chunks after flushing again, got %d", acct.Chunks())
+	}
+
+	var used int64
+	for _, c := range chunks {
+		used += int64(cap(c.bs.stream))
+	}
+
+	if acct.Used() != used {
+		t.Fatalf("expected %d bytes in use, got %d", used, acct.Used())
+	}
+
+	for _, c := range chunks {
+		c.Release()
+	}
+
+	if acct.Used() != 0 || acct.Chunks() != 0 {
+		t.Fatalf("expected nothing in use, got %d bytes, %d chunks", acct.Used(), acct.Chunks())
+	}
+}
@@ -1,84 +1,154 @@
 package chunk
 
 import (
-	"math"
+	"fmt"
 	"time"
 )
 
+type dodBucket struct {
+	dodRange [2]int64
+	dodNBits uint
+}
+
+// dodBuckets buckets indexed by control bits
+type dodBuckets [dodControlBits1111 + 1]dodBucket
+
+type finishMarker struct {
+	bits uint64
+	n    uint
+}
+
 type precisionSettings struct {
-	dod map[uint64]struct {
-		dodRange [2]int32
-		dodNBits uint
-	}
+	// bit size of the first timestamp delta in a block
+	firstDeltaNBits uint
 
-	finish struct {
-		bits uint64
-		n    uint
-	}
+	dod dodBuckets
+
+	finish finishMarker
 }
 
 var precisions = map[time.Duration]precisionSettings{
+	time.Nanosecond: {
+		// with one-day block, we need at most 48 bits
+		firstDeltaNBits: 50,
+
+		dod: dodBuckets{
+			dodControlBits10: {
+				[2]int64{-32768, 32767},
+				16,
+			},
+			dodControlBits110: {
+				[2]int64{-8388608, 8388607},
+				24,
+			},
+			dodControlBits1110: {
+				[2]int64{-34359738368, 34359738367},
+				36,
+			},
+			dodControlBits1111: {
+				[2]int64{-562949953421311, 562949953421311},
+				50,
+			},
+		},
+
+		finish: finishMarker{
+			^uint64(0) >> 14,
+			50,
+		},
+	},
+
+	time.Microsecond: {
+		// with one-day block, we need at most 38 bits
+		firstDeltaNBits: 40,
+
+		dod: dodBuckets{
+			dodControlBits10: {
+				[2]int64{-2048, 2047},
+				12,
+			},
+			dodControlBits110: {
+				[2]int64{-524288, 524287},
+				20,
+			},
+			dodControlBits1110: {
+				[2]int64{-134217728, 134217727},
+				28,
+			},
+			dodControlBits1111: {
+				[2]int64{-549755813887, 549755813887},
+				40,
+			},
+		},
+
+		finish: finishMarker{
+			^uint64(0) >> 24,
+			40,
+		},
+	},
+
 	time.Millisecond: {
-		dod: map[uint64]struct {
-			dodRange [2]int32
-			dodNBits uint
-		}{
+		// with one-day block, we need at most 28 bits
+		firstDeltaNBits: 28,
+
+		dod: dodBuckets{
 			dodControlBits10: {
-				[2]int32{-512, 511},
+				[2]int64{-512, 511},
 				10,
 			},
 			dodControlBits110: {
-				[2]int32{-32768, 32767},
+				[2]int64{-32768, 32767},
 				16,
 			},
 			dodControlBits1110: {
-				[2]int32{-2097152, 2097151},
+				[2]int64{-2097152, 2097151},
 				22,
 			},
 			dodControlBits1111: {
-				[2]int32{math.MinInt32, math.MaxInt32},
+				[2]int64{-134217727, 134217727},
 				28,
 			},
 		},
 
-		finish: struct {
-			bits uint64
-			n    uint
-		}{
+		finish: finishMarker{
 			^uint64(0) >> 36,
 			28,
 		},
 	},
 
 	time.Second: {
-		dod: map[uint64]struct {
-			dodRange [2]int32
-			dodNBits uint
-		}{
+		firstDeltaNBits: 28,
+
+		dod: dodBuckets{
 			dodControlBits10: {
-				[2]int32{-64, 63},
+				[2]int64{-64, 63},
 				7,
 			},
 			dodControlBits110: {
-				[2]int32{-256, 255},
+				[2]int64{-256, 255},
 				9,
 			},
 			dodControlBits1110: {
-				[2]int32{-2048, 2047},
+				[2]int64{-2048, 2047},
 				12,
 			},
 			dodControlBits1111: {
-				[2]int32{math.MinInt32, math.MaxInt32},
+				[2]int64{-2147483647, 2147483647},
 				32,
 			},
 		},
 
-		finish: struct {
-			bits uint64
-			n    uint
-		}{
+		finish: finishMarker{
 			^uint64(0) >> 32,
 			32,
 		},
 	},
 }
+
+func getPrecisionSettings(precision time.Duration) (precisionSettings, error) {
+	settings, ok := precisions[precision]
+	if !ok {
+		return precisionSettings{}, fmt.Errorf("unsupport precision %s", precision)
+	}
+
+	return settings, nil
+}
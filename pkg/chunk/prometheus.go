@@ -0,0 +1,325 @@
+package chunk
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+	"time"
+)
+
+var (
+	// ErrTooManyPoints the points do not fit into a prometheus chunk
+	ErrTooManyPoints = errors.New("too many points")
+)
+
+// prometheus tsdb XOR chunk layout:
+//
+//	num samples(16) | t0 varint | v0(64) | tdelta uvarint | v1 XOR | (dod | v XOR)*
+//
+// timestamps are in milliseconds, the dod buckets are
+//
+//	'0'    for 0
+//	'10'   & 14 bits two's complement
+//	'110'  & 17 bits
+//	'1110' & 20 bits
+//	'1111' & 64 bits
+//
+// the XOR scheme keeps at most 31 leading zeros in a 5 bits field.
+const (
+	promMaxPoints = math.MaxUint16
+
+	promLeadingNBits = 5
+	promMaxLeading   = 1<<promLeadingNBits - 1
+)
+
+var promDoDNBits = [dodControlBits1111 + 1]uint{
+	dodControlBits10:   14,
+	dodControlBits110:  17,
+	dodControlBits1110: 20,
+	dodControlBits1111: 64,
+}
+
+// ToPrometheus encode the points of a float64 chunk, buffered ones included,
+// in the XOR chunk layout of prometheus tsdb.
+// timestamps are converted to milliseconds, points falling into one millisecond keep the first one
+func ToPrometheus(c *Chunk) ([]byte, error) {
+	if c.vtype != TypeFloat64 {
+		return nil, ErrValueType
+	}
+
+	iter, err := c.Iter()
+	if err != nil {
+		return nil, err
+	}
+
+	bs := newBStream(128)
+	bs.writeBits(0, 16)
+
+	var n uint64
+	var t, tdelta int64
+	st := valueState{
+		leading: defaultLeading,
+	}
+
+	for iter.Next() {
+		ts, vbits := iter.Point()
+		ms := convertTimestamp(ts, iter.precision, time.Millisecond)
+		if n > 0 && ms <= t {
+			continue
+		}
+
+		if n == promMaxPoints {
+			return nil, ErrTooManyPoints
+		}
+
+		switch n {
+		case 0:
+			writeVarint(bs, binary.AppendVarint(nil, ms))
+			bs.writeBits(vbits, 64)
+
+		case 1:
+			tdelta = ms - t
+			writeVarint(bs, binary.AppendUvarint(nil, uint64(tdelta)))
+			writePromValue(bs, &st, vbits)
+
+		default:
+			delta := ms - t
+			writePromDoD(bs, delta-tdelta)
+			tdelta = delta
+			writePromValue(bs, &st, vbits)
+		}
+
+		st.vbits = vbits
+		t = ms
+		n++
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	binary.BigEndian.PutUint16(bs.stream, uint16(n))
+	return bs.stream, nil
+}
+
+// FromPrometheus decode a prometheus XOR chunk into a millisecond chunk starting at the first sample
+func FromPrometheus(data []byte, opts ...Option) (*Chunk, error) {
+	if len(data) < 2 {
+		return nil, ErrMalformedStream
+	}
+
+	n := binary.BigEndian.Uint16(data)
+	bs := newBStreamWithData(data[2:])
+	br := bstreamByteReader{bs}
+
+	// the caller's backing array is never appended to
+	opts = append(opts[:len(opts):len(opts)], Type(TypeFloat64))
+
+	var c *Chunk
+	var t, tdelta int64
+	st := valueState{
+		leading: defaultLeading,
+	}
+
+	for k := uint16(0); k < n; k++ {
+		var vbits uint64
+		var err error
+
+		switch k {
+		case 0:
+			t, err = binary.ReadVarint(br)
+			if err != nil {
+				return nil, ErrMalformedStream
+			}
+
+			if vbits, err = bs.readBits(64); err != nil {
+				return nil, ErrMalformedStream
+			}
+
+			c = NewMilliChunk(time.Unix(0, t*int64(time.Millisecond)), opts...)
+
+		case 1:
+			delta, err := binary.ReadUvarint(br)
+			if err != nil {
+				return nil, ErrMalformedStream
+			}
+
+			tdelta = int64(delta)
+			t += tdelta
+			if vbits, err = readPromValue(bs, &st); err != nil {
+				return nil, err
+			}
+
+		default:
+			dod, err := readPromDoD(bs)
+			if err != nil {
+				return nil, err
+			}
+
+			tdelta += dod
+			t += tdelta
+			if vbits, err = readPromValue(bs, &st); err != nil {
+				return nil, err
+			}
+		}
+
+		st.vbits = vbits
+		if err := c.Push(t, vbits); err != nil {
+			return nil, err
+		}
+	}
+
+	if c == nil {
+		c = NewMilliChunk(time.Unix(0, 0), opts...)
+	}
+
+	return c, nil
+}
+
+func writeVarint(bs *bstream, buf []byte) {
+	for _, b := range buf {
+		bs.writeByte(b)
+	}
+}
+
+type bstreamByteReader struct {
+	bs *bstream
+}
+
+func (r bstreamByteReader) ReadByte() (byte, error) {
+	return r.bs.readByte()
+}
+
+// promBitRange check if x fits into the bucket of nbits
+func promBitRange(x int64, nbits uint) bool {
+	return -(1<<(nbits-1)-1) <= x && x <= 1<<(nbits-1)
+}
+
+func writePromDoD(bs *bstream, dod int64) {
+	var ctrl uint64
+	switch {
+	case dod == 0:
+		bs.writeBit(zero)
+		return
+
+	case promBitRange(dod, promDoDNBits[dodControlBits10]):
+		ctrl = dodControlBits10
+		bs.writeBits(ctrl, 2)
+
+	case promBitRange(dod, promDoDNBits[dodControlBits110]):
+		ctrl = dodControlBits110
+		bs.writeBits(ctrl, 3)
+
+	case promBitRange(dod, promDoDNBits[dodControlBits1110]):
+		ctrl = dodControlBits1110
+		bs.writeBits(ctrl, 4)
+
+	default:
+		ctrl = dodControlBits1111
+		bs.writeBits(ctrl, 4)
+	}
+
+	bs.writeBits(uint64(dod), promDoDNBits[ctrl])
+}
+
+func readPromDoD(bs *bstream) (int64, error) {
+	ctrl, err := readDoDControlBits(bs)
+	if err != nil {
+		return 0, ErrMalformedStream
+	}
+
+	nbits := promDoDNBits[ctrl]
+	if nbits == 0 {
+		return 0, nil
+	}
+
+	u, err := bs.readBits(nbits)
+	if err != nil {
+		return 0, ErrMalformedStream
+	}
+
+	// sign extend
+	if nbits < 64 && u > 1<<(nbits-1) {
+		u -= 1 << nbits
+	}
+
+	return int64(u), nil
+}
+
+func writePromValue(bs *bstream, st *valueState, vbits uint64) {
+	vdelta := vbits ^ st.vbits
+	if vdelta == 0 {
+		bs.writeBit(zero)
+		return
+	}
+
+	bs.writeBit(one)
+
+	leading := uint8(bits.LeadingZeros64(vdelta))
+	trailing := uint8(bits.TrailingZeros64(vdelta))
+	if leading > promMaxLeading {
+		leading = promMaxLeading
+	}
+
+	if st.leading != defaultLeading && leading >= st.leading && trailing >= st.trailing {
+		bs.writeBit(zero)
+		bs.writeBits(vdelta>>st.trailing, uint(64-st.leading-st.trailing))
+		return
+	}
+
+	st.leading, st.trailing = leading, trailing
+
+	bs.writeBit(one)
+	bs.writeBits(uint64(leading), promLeadingNBits)
+
+	// 64 meaningful bits will be written as 0
+	meaningfulBits := 64 - leading - trailing
+	bs.writeBits(uint64(meaningfulBits), 6)
+	bs.writeBits(vdelta>>trailing, uint(meaningfulBits))
+}
+
+func readPromValue(bs *bstream, st *valueState) (uint64, error) {
+	ctrl, err := readValueControlBits(bs)
+	if err != nil {
+		return 0, ErrMalformedStream
+	}
+
+	switch ctrl {
+	case valueControlBits0:
+		return st.vbits, nil
+
+	case valueControlBits11:
+		leading, err := bs.readBits(promLeadingNBits)
+		if err != nil {
+			return 0, ErrMalformedStream
+		}
+
+		meaningfulNBits, err := bs.readBits(6)
+		if err != nil {
+			return 0, ErrMalformedStream
+		}
+
+		if meaningfulNBits == 0 {
+			meaningfulNBits = 64
+		}
+
+		if leading+meaningfulNBits > 64 {
+			return 0, ErrMalformedStream
+		}
+
+		st.leading = uint8(leading)
+		st.trailing = uint8(64 - leading - meaningfulNBits)
+	}
+
+	if st.leading == defaultLeading {
+		return 0, ErrMalformedStream
+	}
+
+	meaningful, err := bs.readBits(uint(64 - st.leading - st.trailing))
+	if err != nil {
+		return 0, ErrMalformedStream
+	}
+
+	return st.vbits ^ meaningful<<st.trailing, nil
+}
@@ -0,0 +1,132 @@
+package chunk
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestPrometheusGolden(t *testing.T) {
+	ck := NewMilliChunk(time.Unix(0, 0))
+	ck.PushFloat64(1000, 1)
+	ck.PushFloat64(2000, 1)
+	ck.PushFloat64(3000, 2)
+
+	// derived by hand from the tsdb layout:
+	// num 3 | varint 1000 | 1.0 | uvarint 1000 | '0' | '0' | '11' & 5 bits leading 1 & 6 bits 11 & 0x7ff
+	expected := []byte{
+		0x00, 0x03,
+		0xd0, 0x0f,
+		0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0xe8, 0x07,
+		0x30, 0x97, 0xff, 0xc0,
+	}
+
+	data, err := ToPrometheus(ck)
+	if err != nil {
+		t.Fatalf("to prometheus %s", err)
+	}
+
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("expected % x, got % x", expected, data)
+	}
+}
+
+func TestPrometheusRoundTrip(t *testing.T) {
+	baseT := time.Now().Truncate(time.Hour)
+	t0 := baseT.Unix()
+
+	ck := NewChunk(baseT)
+
+	var times []int64
+	var values []float64
+	tm := t0
+	for i := 0; i < 5000; i++ {
+		// large gaps exercise the wider dod buckets
+		switch i % 100 {
+		case 50:
+			tm += 1000
+		case 99:
+			tm += 1 + rand.Int63n(100000)
+		default:
+			tm += 15
+		}
+
+		v := math.Round(rand.NormFloat64()*1000) / 10
+		// repeated values, and values differing in the lowest bits which exceed the 31 leading zeros
+		switch {
+		case i%7 == 1:
+			v = values[len(values)-1]
+		case i%13 == 2:
+			v = math.Nextafter(values[len(values)-1], math.Inf(1))
+		}
+
+		ck.PushFloat64(tm, v)
+		times = append(times, tm)
+		values = append(values, v)
+	}
+
+	data, err := ToPrometheus(ck)
+	if err != nil {
+		t.Fatalf("to prometheus %s", err)
+	}
+
+	restored, err := FromPrometheus(data)
+	if err != nil {
+		t.Fatalf("from prometheus %s", err)
+	}
+
+	iter, err := restored.Iter()
+	if err != nil {
+		t.Fatalf("new iter %s", err)
+	}
+
+	n := 0
+	for iter.Next() {
+		ts, v := iter.PointFloat64()
+		if ts != times[n]*1000 || v != values[n] {
+			t.Fatalf("#%d: expected (%d, %f), got (%d, %f)", n, times[n]*1000, values[n], ts, v)
+		}
+
+		n++
+	}
+
+	if n != len(times) {
+		t.Fatalf("expected %d points, got %d", len(times), n)
+	}
+
+	again, err := ToPrometheus(restored)
+	if err != nil {
+		t.Fatalf("to prometheus %s", err)
+	}
+
+	if !bytes.Equal(again, data) {
+		t.Fatalf("expected identical bytes after round trip")
+	}
+
+	if _, err := ToPrometheus(NewChunk(baseT, Type(TypeInt64))); err != ErrValueType {
+		t.Fatalf("expected ErrValueType, got %v", err)
+	}
+
+	if _, err := FromPrometheus(data[:len(data)/2]); err != ErrMalformedStream {
+		t.Fatalf("expected ErrMalformedStream for truncated chunk, got %v", err)
+	}
+
+	empty, err := FromPrometheus([]byte{0, 0})
+	if err != nil || empty.Num() != 0 {
+		t.Fatalf("expected empty chunk, got %v", err)
+	}
+
+	// spare capacity of the caller's options is left untouched
+	opts := make([]Option, 1, 2)
+	opts[0] = Checkpoints(10)
+	if _, err := FromPrometheus(data, opts...); err != nil {
+		t.Fatalf("from prometheus with options %s", err)
+	}
+
+	if opts[:2][1] != nil {
+		t.Fatalf("expected the options not to be appended in place")
+	}
+}
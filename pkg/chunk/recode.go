@@ -0,0 +1,220 @@
+package chunk
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNoSource no chunk to recode
+	ErrNoSource = errors.New("no source chunk")
+
+	// ErrMixedTypes the source chunks have different value types
+	ErrMixedTypes = errors.New("mixed value types")
+
+	// ErrMixedPrecisions the source chunks have different precisions
+	ErrMixedPrecisions = errors.New("mixed precisions")
+
+	// ErrMixedLossy the source chunks round float64 values differently
+	ErrMixedLossy = errors.New("mixed lossy settings")
+)
+
+// RecodeOptions options for re-encoding chunks, zero values keep the source settings
+type RecodeOptions struct {
+	// Precision of the new chunk. When it is coarser than the source's,
+	// timestamps are truncated and points falling into one tick are deduplicated with Dedup
+	Precision time.Duration
+
+	// Dedup rule for overlapping sources and truncated timestamps
+	Dedup Dedup
+
+	// Since & Until points outside [Since, Until) are dropped
+	Since time.Time
+	Until time.Time
+
+	// Start of the new block, default the earliest start of the sources
+	Start time.Time
+
+	// Window of the new block, default unbounded, see Window
+	Window time.Duration
+
+	// Checkpoints interval of the new chunk
+	Checkpoints int
+}
+
+// RecodeReport sizes before & after re-encoding
+type RecodeReport struct {
+	PointsIn  uint64
+	PointsOut uint64
+	BytesIn   int
+	BytesOut  int
+}
+
+// RatioIn return the compression ratio of the source chunks, against 16 bytes per point
+func (r RecodeReport) RatioIn() float64 {
+	return ratio(r.PointsIn, r.BytesIn)
+}
+
+// RatioOut return the compression ratio of the new chunk, against 16 bytes per point
+func (r RecodeReport) RatioOut() float64 {
+	return ratio(r.PointsOut, r.BytesOut)
+}
+
+func ratio(points uint64, size int) float64 {
+	if size == 0 {
+		return 0
+	}
+
+	return float64(points*16) / float64(size)
+}
+
+// Recode decode the chunk and encode the points again with given options
+func Recode(src *Chunk, opts RecodeOptions) (*Chunk, RecodeReport, error) {
+	return Concat(opts, src)
+}
+
+// Concat merge adjacent or overlapping chunks of one series into one larger block,
+// the sources must share the value type, the precision & the lossy settings,
+// which are kept by the new chunk
+func Concat(opts RecodeOptions, srcs ...*Chunk) (*Chunk, RecodeReport, error) {
+	var report RecodeReport
+
+	if len(srcs) == 0 {
+		return nil, report, ErrNoSource
+	}
+
+	first := srcs[0]
+	first.RLock()
+	srcPrec, vtype, t0, srcLossy := first.precision, first.vtype, first.t0, first.lossy
+	srcPolicy, srcLag := first.policy, time.Duration(first.lag)*first.precision
+	first.RUnlock()
+
+	dstPrec := opts.Precision
+	if dstPrec == 0 {
+		dstPrec = srcPrec
+	}
+
+	policy := PolicyDrop
+	if opts.Dedup == DedupLast {
+		policy = PolicyLastWriteWins
+	}
+
+	settings, err := getPrecisionSettings(dstPrec)
+	if err != nil {
+		return nil, report, err
+	}
+
+	iters := make([]*Iter, len(srcs))
+	finished := true
+	for i, src := range srcs {
+		src.RLock()
+		report.PointsIn += src.num + uint64(len(src.buf))
+		report.BytesIn += len(src.bs.stream)
+		finished = finished && src.finished
+		if src.t0 < t0 {
+			t0 = src.t0
+		}
+
+		sameType, samePrec, sameLossy := src.vtype == vtype, src.precision == srcPrec, src.lossy == srcLossy
+		src.RUnlock()
+
+		if !sameType {
+			return nil, report, ErrMixedTypes
+		}
+
+		// the merge iterator orders points by ticks
+		if !samePrec {
+			return nil, report, ErrMixedPrecisions
+		}
+
+		if !sameLossy {
+			return nil, report, ErrMixedLossy
+		}
+
+		iter, err := src.Iter()
+		if err != nil {
+			return nil, report, err
+		}
+
+		iters[i] = iter
+	}
+
+	start := opts.Start
+	if start.IsZero() {
+		start = time.Unix(0, t0*int64(srcPrec))
+	}
+
+	dst := newChunk(start, dstPrec, settings, Type(vtype), Order(policy), Checkpoints(opts.Checkpoints), Window(opts.Window))
+
+	mi := NewMergeIter(opts.Dedup, iters...)
+	for mi.Next() {
+		cur := mi.Current()
+		ts, vbits := cur.Point()
+
+		ns := ts * int64(cur.precision)
+		if !opts.Since.IsZero() && ns < opts.Since.UnixNano() {
+			continue
+		}
+
+		if !opts.Until.IsZero() && ns >= opts.Until.UnixNano() {
+			continue
+		}
+
+		ts = convertTimestamp(ts, cur.precision, dstPrec)
+
+		if vtype == TypeString {
+			_, str := cur.PointString()
+			err = dst.PushString(ts, str)
+		} else {
+			err = dst.Push(ts, vbits)
+		}
+
+		if err != nil {
+			return nil, report, err
+		}
+	}
+
+	if err := mi.Err(); err != nil {
+		return nil, report, err
+	}
+
+	// values are rounded already, later points are rounded as the source did
+	dst.lossy = srcLossy
+
+	if finished {
+		if err := dst.Finish(); err != nil {
+			return nil, report, err
+		}
+	} else {
+		if err := dst.flush(true); err != nil {
+			return nil, report, err
+		}
+
+		// the open chunk keeps accepting points like the source did
+		dst.policy, dst.lag = srcPolicy, int64(srcLag/dstPrec)
+		dst.invalidate()
+	}
+
+	report.PointsOut = dst.num
+	report.BytesOut = len(dst.bs.stream)
+
+	return dst, report, nil
+}
+
+// convertTimestamp convert timestamp between precisions, truncating toward the past
+func convertTimestamp(ts int64, from, to time.Duration) int64 {
+	if from == to {
+		return ts
+	}
+
+	if from > to {
+		return ts * int64(from/to)
+	}
+
+	factor := int64(to / from)
+	if ts < 0 && ts%factor != 0 {
+		return ts/factor - 1
+	}
+
+	return ts / factor
+}
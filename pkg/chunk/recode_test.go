@@ -0,0 +1,167 @@
+package chunk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecode(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+	t0 := baseT.UnixNano() / int64(time.Millisecond)
+
+	src := NewMilliChunk(baseT)
+	for i := int64(0); i < 3000; i++ {
+		// 4 points per second
+		if err := src.Push(t0+i*250, uint64(i)); err != nil {
+			t.Fatalf("push %s", err)
+		}
+	}
+
+	src.Finish()
+
+	for _, dedup := range []Dedup{DedupFirst, DedupLast} {
+		dst, report, err := Recode(src, RecodeOptions{
+			Precision: time.Second,
+			Dedup:     dedup,
+			Since:     baseT.Add(100 * time.Second),
+			Until:     baseT.Add(600 * time.Second),
+		})
+		if err != nil {
+			t.Fatalf("recode %s", err)
+		}
+
+		if report.PointsIn != 3000 || report.PointsOut != 500 || dst.Num() != 500 {
+			t.Fatalf("expected 3000 -> 500 points, got %+v", report)
+		}
+
+		if report.RatioIn() <= 0 || report.RatioOut() <= 0 {
+			t.Fatalf("unexpected ratio %+v", report)
+		}
+
+		iter, err := dst.Iter()
+		if err != nil {
+			t.Fatalf("new iter %s", err)
+		}
+
+		expected := baseT.Unix() + 100
+		for iter.Next() {
+			ts, v := iter.Point()
+			if ts != expected {
+				t.Fatalf("expected ts %d, got %d", expected, ts)
+			}
+
+			ev := uint64((ts - baseT.Unix()) * 4)
+			if dedup == DedupLast {
+				ev += 3
+			}
+
+			if v != ev {
+				t.Fatalf("dedup %d: expected value %d at %d, got %d", dedup, ev, ts, v)
+			}
+
+			expected++
+		}
+
+		if err := iter.Err(); err != nil {
+			t.Fatalf("iter %s", err)
+		}
+	}
+}
+
+func TestConcat(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+	t0 := baseT.Unix()
+
+	var srcs []*Chunk
+	for b := int64(0); b < 3; b++ {
+		ck := NewChunk(baseT.Add(time.Duration(b*1000) * time.Second))
+		for i := int64(0); i < 1000; i++ {
+			ck.Push(t0+b*1000+i, uint64(b*1000+i))
+		}
+
+		ck.Finish()
+		srcs = append(srcs, ck)
+	}
+
+	dst, report, err := Concat(RecodeOptions{}, srcs...)
+	if err != nil {
+		t.Fatalf("concat %s", err)
+	}
+
+	if report.PointsOut != 3000 || dst.MinTime() != t0 || dst.MaxTime() != t0+2999 {
+		t.Fatalf("unexpected result %+v", report)
+	}
+
+	if report.BytesOut >= report.BytesIn {
+		t.Fatalf("expected smaller output %+v", report)
+	}
+
+	iter, _ := dst.Iter()
+	for i := int64(0); iter.Next(); i++ {
+		ts, v := iter.Point()
+		if ts != t0+i || v != uint64(i) {
+			t.Fatalf("expected (%d, %d), got (%d, %d)", t0+i, i, ts, v)
+		}
+	}
+
+	// the block starts at the earliest source whatever the order of the sources
+	dst, _, err = Concat(RecodeOptions{}, srcs[2], srcs[0], srcs[1])
+	if err != nil {
+		t.Fatalf("concat %s", err)
+	}
+
+	if dst.Start() != t0 || dst.Num() != 3000 {
+		t.Fatalf("expected the block to start at %d with 3000 points, got %d with %d", t0, dst.Start(), dst.Num())
+	}
+
+	if _, _, err := Concat(RecodeOptions{}, srcs[0], NewChunk(baseT, Type(TypeString))); err != ErrMixedTypes {
+		t.Fatalf("expected ErrMixedTypes, got %v", err)
+	}
+
+	secs, millis := NewChunk(baseT), NewMilliChunk(baseT)
+	secs.Push(t0+10, 1)
+	secs.Push(t0+20, 2)
+	millis.Push((t0+5)*1000, 3)
+	millis.Push((t0+15)*1000, 4)
+	if _, _, err := Concat(RecodeOptions{}, secs, millis); err != ErrMixedPrecisions {
+		t.Fatalf("expected ErrMixedPrecisions, got %v", err)
+	}
+
+	rounded := []*Chunk{NewChunk(baseT, MantissaBits(10)), NewChunk(baseT.Add(time.Minute), MantissaBits(10))}
+	for i, ck := range rounded {
+		ck.PushFloat64(t0+int64(i)*60, 1.2345)
+	}
+
+	dst, _, err = Concat(RecodeOptions{}, rounded...)
+	if err != nil {
+		t.Fatalf("concat %s", err)
+	}
+
+	if l := dst.Lossy(); l != rounded[0].Lossy() {
+		t.Fatalf("expected lossy settings of the sources, got %s", l)
+	}
+
+	if _, _, err := Concat(RecodeOptions{}, rounded[0], NewChunk(baseT.Add(time.Minute))); err != ErrMixedLossy {
+		t.Fatalf("expected ErrMixedLossy, got %v", err)
+	}
+}
+
+func TestConvertTimestamp(t *testing.T) {
+	cases := []struct {
+		ts       int64
+		from, to time.Duration
+		expected int64
+	}{
+		{1500, time.Millisecond, time.Second, 1},
+		{-1500, time.Millisecond, time.Second, -2},
+		{-2000, time.Millisecond, time.Second, -2},
+		{3, time.Second, time.Millisecond, 3000},
+		{7, time.Second, time.Second, 7},
+	}
+
+	for _, c := range cases {
+		if got := convertTimestamp(c.ts, c.from, c.to); got != c.expected {
+			t.Fatalf("convert %d from %s to %s: expected %d, got %d", c.ts, c.from, c.to, c.expected, got)
+		}
+	}
+}
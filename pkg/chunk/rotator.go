@@ -0,0 +1,153 @@
+package chunk
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidWindow the window is not a positive multiple of the precision, or is too large for the first delta
+	ErrInvalidWindow = errors.New("invalid chunk window")
+)
+
+// NewRotator return a rotator writing points into consecutive chunks of the given window,
+// blocks are aligned to multiples of the window since the unix epoch.
+// onFinish is called with every chunk finished by rotation or Close
+func NewRotator(precision, window time.Duration, onFinish func(c *Chunk) error, opts ...Option) (*Rotator, error) {
+	settings, err := getPrecisionSettings(precision)
+	if err != nil {
+		return nil, err
+	}
+
+	if window < precision || window%precision != 0 {
+		return nil, ErrInvalidWindow
+	}
+
+	// the last tick of the window must be encodable as the first delta
+	ticks := int64(window / precision)
+	if zigzag64(ticks-1)>>settings.firstDeltaNBits != 0 {
+		return nil, ErrInvalidWindow
+	}
+
+	return &Rotator{
+		precision: precision,
+		settings:  settings,
+		window:    window,
+		ticks:     ticks,
+		opts:      append(opts[:len(opts):len(opts)], Window(window)),
+		onFinish:  onFinish,
+	}, nil
+}
+
+// Rotator write points of one series, the current chunk is finished
+// and a new one is started when a point crosses the end of its window
+type Rotator struct {
+	sync.Mutex
+
+	precision time.Duration
+	settings  precisionSettings
+	window    time.Duration
+	ticks     int64
+	opts      []Option
+
+	onFinish func(c *Chunk) error
+
+	cur *Chunk
+}
+
+// Push push timestamp and value bits
+func (r *Rotator) Push(t int64, vbits uint64) error {
+	return r.push(t, func(c *Chunk) error {
+		return c.Push(t, vbits)
+	})
+}
+
+// PushFloat64 push timestamp and float64 value
+func (r *Rotator) PushFloat64(t int64, v float64) error {
+	return r.Push(t, math.Float64bits(v))
+}
+
+// PushInt64 push timestamp and int64 value
+func (r *Rotator) PushInt64(t int64, v int64) error {
+	return r.Push(t, uint64(v))
+}
+
+// PushBool push timestamp and bool value
+func (r *Rotator) PushBool(t int64, v bool) error {
+	return r.push(t, func(c *Chunk) error {
+		return c.PushBool(t, v)
+	})
+}
+
+// PushString push timestamp and string value, the chunks must be created with Type(TypeString)
+func (r *Rotator) PushString(t int64, v string) error {
+	return r.push(t, func(c *Chunk) error {
+		return c.PushString(t, v)
+	})
+}
+
+func (r *Rotator) push(t int64, fn func(c *Chunk) error) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.cur != nil && t >= r.cur.End() {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if r.cur == nil {
+		start := r.blockStart(t)
+		r.cur = newChunk(time.Unix(0, start*int64(r.precision)), r.precision, r.settings, r.opts...)
+	}
+
+	// points older than the current block are rejected by the chunk
+	return fn(r.cur)
+}
+
+// blockStart return the start timestamp of the block t falls into
+func (r *Rotator) blockStart(t int64) int64 {
+	start := t - t%r.ticks
+	if t < 0 && t%r.ticks != 0 {
+		start -= r.ticks
+	}
+
+	return start
+}
+
+// rotate finish the current chunk and hand it over, must be called with the lock held
+func (r *Rotator) rotate() error {
+	c := r.cur
+	r.cur = nil
+
+	err := c.Finish()
+	if r.onFinish != nil {
+		if e := r.onFinish(c); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// Current return the chunk being written, nil if there is none
+func (r *Rotator) Current() *Chunk {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.cur
+}
+
+// Close finish the current chunk and hand it over
+func (r *Rotator) Close() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.cur == nil {
+		return nil
+	}
+
+	return r.rotate()
+}
@@ -0,0 +1,91 @@
+package chunk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRotator(t *testing.T) {
+	if _, err := NewRotator(time.Second, time.Millisecond, nil); err != ErrInvalidWindow {
+		t.Fatalf("expected ErrInvalidWindow for window shorter than precision, got %v", err)
+	}
+
+	if _, err := NewRotator(time.Millisecond, 7*24*time.Hour, nil); err != ErrInvalidWindow {
+		t.Fatalf("expected ErrInvalidWindow for window overflowing the first delta, got %v", err)
+	}
+
+	var finished []*Chunk
+	r, err := NewRotator(time.Second, time.Hour, func(c *Chunk) error {
+		finished = append(finished, c)
+		return nil
+	}, Checkpoints(16))
+	if err != nil {
+		t.Fatalf("new rotator %s", err)
+	}
+
+	if r.Current() != nil {
+		t.Fatalf("expected no current chunk")
+	}
+
+	t0 := time.Now().Truncate(time.Hour).Unix()
+	start := t0 + 1800
+
+	var expected []int64
+	for ts := start; ts < start+3*3600; ts += 7 {
+		if err := r.PushFloat64(ts, float64(ts)); err != nil {
+			t.Fatalf("push %d %s", ts, err)
+		}
+
+		expected = append(expected, ts)
+	}
+
+	// a point of an older block
+	if err := r.PushFloat64(t0+3600-1, 1); err != ErrOutOfWindow {
+		t.Fatalf("expected ErrOutOfWindow, got %v", err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("close %s", err)
+	}
+
+	if r.Current() != nil {
+		t.Fatalf("expected no current chunk after close")
+	}
+
+	if len(finished) != 4 {
+		t.Fatalf("expected 4 chunks, got %d", len(finished))
+	}
+
+	idx := 0
+	for k, c := range finished {
+		if c.Start() != t0+int64(k)*3600 || c.End() != c.Start()+3600 {
+			t.Fatalf("chunk #%d: unexpected window [%d, %d)", k, c.Start(), c.End())
+		}
+
+		if !c.Full() {
+			t.Fatalf("chunk #%d: expected finished chunk", k)
+		}
+
+		iter, err := c.Iter()
+		if err != nil {
+			t.Fatalf("new iter %s", err)
+		}
+
+		for iter.Next() {
+			ts, v := iter.PointFloat64()
+			if ts != expected[idx] || v != float64(ts) {
+				t.Fatalf("chunk #%d: expected point %d, got (%d, %f)", k, expected[idx], ts, v)
+			}
+
+			idx++
+		}
+
+		if err := iter.Err(); err != nil {
+			t.Fatalf("iter %s", err)
+		}
+	}
+
+	if idx != len(expected) {
+		t.Fatalf("expected %d points, got %d", len(expected), idx)
+	}
+}
@@ -0,0 +1,67 @@
+package chunk
+
+import "strconv"
+
+// Stats compression statistics of the points read by an iterator
+type Stats struct {
+	Points uint64
+
+	// bits spent on timestamps & values, the end-of-stream record is not included
+	TimestampBits uint64
+	ValueBits     uint64
+
+	// number of points per control bits case, the first point is not included.
+	// for float64 & string values the keys are the xor / dictionary control bits,
+	// for int64 values the delta-of-delta control bits, for bool values 1 for the points starting a run, 0 for the others
+	DoD   map[uint64]int
+	Value map[uint64]int
+}
+
+func (s *Stats) add(tbits, vbits uint64) {
+	s.Points++
+	s.TimestampBits += tbits
+	s.ValueBits += vbits
+}
+
+func (s Stats) clone() Stats {
+	c := s
+	c.DoD = make(map[uint64]int, len(s.DoD))
+	for k, n := range s.DoD {
+		c.DoD[k] = n
+	}
+
+	c.Value = make(map[uint64]int, len(s.Value))
+	for k, n := range s.Value {
+		c.Value[k] = n
+	}
+
+	return c
+}
+
+// BitsPerPoint return the average bits per point
+func (s Stats) BitsPerPoint() float64 {
+	return perPoint(s.TimestampBits+s.ValueBits, s.Points)
+}
+
+// TimestampBitsPerPoint return the average timestamp bits per point
+func (s Stats) TimestampBitsPerPoint() float64 {
+	return perPoint(s.TimestampBits, s.Points)
+}
+
+// ValueBitsPerPoint return the average value bits per point
+func (s Stats) ValueBitsPerPoint() float64 {
+	return perPoint(s.ValueBits, s.Points)
+}
+
+func perPoint(bits, points uint64) float64 {
+	if points == 0 {
+		return 0
+	}
+
+	return float64(bits) / float64(points)
+}
+
+// ControlBits format control bits as they are written, e.g. "110"
+func ControlBits(ctrl uint64) string {
+	return strconv.FormatUint(ctrl, 2)
+}
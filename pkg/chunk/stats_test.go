@@ -0,0 +1,68 @@
+package chunk
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestIterStats(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+	t0 := baseT.Unix()
+
+	ck := NewChunk(baseT)
+	ts := t0
+	for i := 0; i < 100; i++ {
+		// regular interval & constant value except for every 10th point
+		ts += 10
+		v := uint64(0)
+		if i%10 == 9 {
+			ts++
+			v = uint64(i)
+		}
+
+		if err := ck.PushInt64(ts, int64(v)); err != nil {
+			t.Fatalf("push %s", err)
+		}
+	}
+
+	iter, err := ck.Iter()
+	if err != nil {
+		t.Fatalf("new iter %s", err)
+	}
+
+	iter.PointStat(true)
+	for iter.Next() {
+	}
+
+	stats := iter.Stats()
+	if stats.Points != 100 {
+		t.Fatalf("expected 100 points, got %d", stats.Points)
+	}
+
+	dods := 0
+	for _, n := range stats.DoD {
+		dods += n
+	}
+
+	if dods != 99 {
+		t.Fatalf("expected 99 dod cases, got %d", dods)
+	}
+
+	if stats.DoD[dodControlBits0] == 0 || stats.DoD[dodControlBits10] == 0 {
+		t.Fatalf("unexpected dod histogram %v", stats.DoD)
+	}
+
+	// first point: first delta & raw value bits; the others at least one control bit each
+	if stats.TimestampBits < uint64(ck.firstDeltaNBits)+99 || stats.ValueBits < 64+99 {
+		t.Fatalf("unexpected bits %+v", stats)
+	}
+
+	if bpp := stats.BitsPerPoint(); math.Abs(bpp-stats.TimestampBitsPerPoint()-stats.ValueBitsPerPoint()) > 1e-9 {
+		t.Fatalf("unexpected bits per point %f", bpp)
+	}
+
+	if ControlBits(dodControlBits1110) != "1110" {
+		t.Fatalf("unexpected control bits format %s", ControlBits(dodControlBits1110))
+	}
+}
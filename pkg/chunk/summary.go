@@ -0,0 +1,150 @@
+package chunk
+
+import (
+	"math"
+)
+
+// Summary statistics of numeric points
+type Summary struct {
+	Count uint64
+
+	Min   float64
+	Max   float64
+	Sum   float64
+	First float64
+	Last  float64
+
+	FirstTime int64
+	LastTime  int64
+
+	// running mean & sum of squares of differences from the mean, see Welford's algorithm
+	Mean float64
+	M2   float64
+}
+
+// Add add a point into the summary
+func (s *Summary) Add(t int64, v float64) {
+	if s.Count == 0 {
+		s.Count = 1
+		s.Min, s.Max, s.Sum = v, v, v
+		s.First, s.Last = v, v
+		s.FirstTime, s.LastTime = t, t
+		s.Mean, s.M2 = v, 0
+		return
+	}
+
+	s.Count++
+	s.Min = math.Min(s.Min, v)
+	s.Max = math.Max(s.Max, v)
+	s.Sum += v
+
+	if t < s.FirstTime {
+		s.First, s.FirstTime = v, t
+	}
+
+	if t >= s.LastTime {
+		s.Last, s.LastTime = v, t
+	}
+
+	delta := v - s.Mean
+	s.Mean += delta / float64(s.Count)
+	s.M2 += delta * (v - s.Mean)
+}
+
+// Merge merge another summary
+func (s *Summary) Merge(o Summary) {
+	if o.Count == 0 {
+		return
+	}
+
+	if s.Count == 0 {
+		*s = o
+		return
+	}
+
+	n := s.Count + o.Count
+	delta := o.Mean - s.Mean
+
+	s.Min = math.Min(s.Min, o.Min)
+	s.Max = math.Max(s.Max, o.Max)
+	s.Sum += o.Sum
+
+	if o.FirstTime < s.FirstTime {
+		s.First, s.FirstTime = o.First, o.FirstTime
+	}
+
+	if o.LastTime >= s.LastTime {
+		s.Last, s.LastTime = o.Last, o.LastTime
+	}
+
+	s.Mean += delta * float64(o.Count) / float64(n)
+	s.M2 += o.M2 + delta*delta*float64(s.Count)*float64(o.Count)/float64(n)
+	s.Count = n
+}
+
+// Avg return the average value, NaN if empty
+func (s Summary) Avg() float64 {
+	if s.Count == 0 {
+		return math.NaN()
+	}
+
+	return s.Sum / float64(s.Count)
+}
+
+// Stddev return the population standard deviation, NaN if empty
+func (s Summary) Stddev() float64 {
+	if s.Count == 0 {
+		return math.NaN()
+	}
+
+	return math.Sqrt(s.M2 / float64(s.Count))
+}
+
+// Float64 convert value bits into float64, return false for string values
+func (vt ValueType) Float64(vbits uint64) (float64, bool) {
+	switch vt {
+	case TypeFloat64:
+		return math.Float64frombits(vbits), true
+
+	case TypeInt64:
+		return float64(int64(vbits)), true
+
+	case TypeBool:
+		if vbits != 0 {
+			return 1, true
+		}
+
+		return 0, true
+
+	default:
+		return 0, false
+	}
+}
+
+func writeSummary(w *bwriter, s Summary) {
+	w.write(s.Count)
+	w.write(s.Min)
+	w.write(s.Max)
+	w.write(s.Sum)
+	w.write(s.First)
+	w.write(s.Last)
+	w.write(s.FirstTime)
+	w.write(s.LastTime)
+	w.write(s.Mean)
+	w.write(s.M2)
+}
+
+func readSummary(r *breader) Summary {
+	var s Summary
+	r.read(&s.Count)
+	r.read(&s.Min)
+	r.read(&s.Max)
+	r.read(&s.Sum)
+	r.read(&s.First)
+	r.read(&s.Last)
+	r.read(&s.FirstTime)
+	r.read(&s.LastTime)
+	r.read(&s.Mean)
+	r.read(&s.M2)
+	return s
+}
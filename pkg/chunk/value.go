@@ -0,0 +1,542 @@
+package chunk
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// ValueType type of the values in a chunk
+type ValueType uint8
+
+const (
+	// TypeFloat64 float64 values, encoded with gorilla's XOR scheme
+	TypeFloat64 ValueType = iota
+	// TypeInt64 int64 values, encoded with delta-of-delta & zigzag
+	TypeInt64
+	// TypeBool bool values, run-length encoded: the first point of a run writes the value bit & the run length
+	TypeBool
+	// TypeString string values, dictionary encoded
+	TypeString
+)
+
+func (vt ValueType) String() string {
+	switch vt {
+	case TypeFloat64:
+		return "float64"
+
+	case TypeInt64:
+		return "int64"
+
+	case TypeBool:
+		return "bool"
+
+	case TypeString:
+		return "string"
+
+	default:
+		return fmt.Sprintf("ValueType(%d)", uint8(vt))
+	}
+}
+
+func (vt ValueType) valid() bool {
+	return vt <= TypeString
+}
+
+// the type tag is stored in the highest byte of the point num field in stream header
+const (
+	typeTagShift        = 56
+	numMask      uint64 = 1<<typeTagShift - 1
+)
+
+func headerWord(vt ValueType, num uint64) uint64 {
+	return uint64(vt)<<typeTagShift | num&numMask
+}
+
+func parseHeaderWord(word uint64) (ValueType, uint64) {
+	return ValueType(word >> typeTagShift), word & numMask
+}
+
+const (
+	// int64 delta-of-delta buckets
+	//   '10' for [-128, 127]
+	//  '110' for [-32768, 32767]
+	// '1110' for [-2147483648, 2147483647]
+	// '1111' for others
+	intDoDNBits10   = 8
+	intDoDNBits110  = 16
+	intDoDNBits1110 = 32
+	intDoDNBits1111 = 64
+
+	// string length field
+	strLenNBits = 32
+
+	// bool run length buckets, the length - 1 is written after the control bits
+	//   '0' for 1
+	//  '10' for [2, 8]
+	// '110' for [9, 32]
+	// '111' for [33, 256]
+	runControlBits0   uint64 = 0x00
+	runControlBits10         = 0x02
+	runControlBits110        = 0x06
+	runControlBits111        = 0x07
+
+	runNBits10  = 3
+	runNBits110 = 5
+	runNBits111 = 8
+
+	// longer runs are split, the length of a run is known before its points are written,
+	// so the open run of a bool chunk is kept buffered, see Chunk.flush
+	maxBoolRun = 1 << runNBits111
+)
+
+var intDoDBuckets = dodBuckets{
+	dodControlBits10: {
+		[2]int64{-128, 127},
+		intDoDNBits10,
+	},
+	dodControlBits110: {
+		[2]int64{-32768, 32767},
+		intDoDNBits110,
+	},
+	dodControlBits1110: {
+		[2]int64{math.MinInt32, math.MaxInt32},
+		intDoDNBits1110,
+	},
+	dodControlBits1111: {
+		[2]int64{math.MinInt64, math.MaxInt64},
+		intDoDNBits1111,
+	},
+}
+
+// valueState value encoding state shared by encoder & decoder
+type valueState struct {
+	vbits    uint64
+	leading  uint8
+	trailing uint8
+
+	// delta of previous int64 value
+	vdelta int64
+
+	// number of string values written into the stream
+	dictN uint64
+
+	// points left in the current run of bool values
+	run uint64
+}
+
+// writeValue write value bits of the first or following points, str is the new dictionary entry for string chunk,
+// run is the length of the run a bool point starts, 0 for the following points of the run
+func writeValue(bs *bstream, vt ValueType, st *valueState, vbits uint64, str string, run uint64, first bool) {
+	switch vt {
+	case TypeInt64:
+		writeInt64Value(bs, st, vbits, first)
+
+	case TypeBool:
+		writeBoolValue(bs, st, vbits, run)
+
+	case TypeString:
+		writeStringValue(bs, st, vbits, str, first)
+
+	default:
+		writeFloat64Value(bs, st, vbits, first)
+	}
+
+	st.vbits = vbits
+}
+
+func writeFloat64Value(bs *bstream, st *valueState, vbits uint64, first bool) {
+	if first {
+		bs.writeBits(vbits, 64)
+		return
+	}
+
+	vdelta := vbits ^ st.vbits
+	if vdelta == 0 {
+		bs.writeBit(zero)
+		return
+	}
+
+	bs.writeBit(one)
+
+	// When XOR is non-zero, calculate the number of leading
+	// and trailing zeros in the XOR, store bit ‘1’ followed
+	// by either a) or b):
+	// 		(a) (Control bit ‘0’) If the block of meaningful bits
+	//           falls within the block of previous meaningful bits,
+	//           i.e., there are at least as many leading zeros and
+	//           as many trailing zeros as with the previous value,
+	// 		     use that information for the block position and
+	//           just store the meaningful XORed value.
+	// 		(b) (Control bit ‘1’) Store the length of the number
+	//           of leading zeros in the next 5 bits, then store the
+	//           length of the meaningful XORed value in the next
+	//           6 bits. Finally store the meaningful bits of the
+	//           XORed value.
+
+	leading := uint8(bits.LeadingZeros64(vdelta))
+	trailing := uint8(bits.TrailingZeros64(vdelta))
+
+	// leading has been set and for the meaningful bit, previous size >= current size
+	if st.leading != defaultLeading && leading >= st.leading && trailing >= st.trailing {
+		bs.writeBit(zero)
+		bs.writeBits(vdelta>>st.trailing, uint(64-st.leading-st.trailing))
+		return
+	}
+
+	st.leading, st.trailing = leading, trailing
+
+	bs.writeBit(one)
+	// we use 6 bit for storing leading size to support at most 63 bit leading zeros
+	bs.writeBits(uint64(leading), 6)
+
+	// 64 meaningful bits will be written as 0, since the XOR can not be 0 here
+	meaningfulBits := 64 - leading - trailing
+	bs.writeBits(uint64(meaningfulBits), 6)
+	bs.writeBits(vdelta>>trailing, uint(meaningfulBits))
+}
+
+func writeInt64Value(bs *bstream, st *valueState, vbits uint64, first bool) {
+	if first {
+		bs.writeBits(vbits, 64)
+		st.vdelta = 0
+		return
+	}
+
+	vdelta := int64(vbits) - int64(st.vbits)
+	dod := vdelta - st.vdelta
+	st.vdelta = vdelta
+
+	var ctrlBits uint64
+	var nbits uint
+
+	switch {
+	case dod == 0:
+		bs.writeBit(zero)
+		return
+
+	case inRange(dod, intDoDBuckets[dodControlBits10].dodRange):
+		ctrlBits, nbits = dodControlBits10, 2
+
+	case inRange(dod, intDoDBuckets[dodControlBits110].dodRange):
+		ctrlBits, nbits = dodControlBits110, 3
+
+	case inRange(dod, intDoDBuckets[dodControlBits1110].dodRange):
+		ctrlBits, nbits = dodControlBits1110, 4
+
+	default:
+		ctrlBits, nbits = dodControlBits1111, 4
+	}
+
+	bs.writeBits(ctrlBits, nbits)
+	bs.writeBits(zigzag64(dod), intDoDBuckets[ctrlBits].dodNBits)
+}
+
+func writeBoolValue(bs *bstream, st *valueState, vbits uint64, run uint64) {
+	// following points of the run write nothing
+	if st.run > 0 {
+		st.run--
+		return
+	}
+
+	if run == 0 {
+		run = 1
+	}
+
+	bs.writeBit(vbits != 0)
+	switch n := run - 1; {
+	case n == 0:
+		bs.writeBit(zero)
+
+	case n < 1<<runNBits10:
+		bs.writeBits(runControlBits10, 2)
+		bs.writeBits(n, runNBits10)
+
+	case n < 1<<runNBits110:
+		bs.writeBits(runControlBits110, 3)
+		bs.writeBits(n, runNBits110)
+
+	default:
+		bs.writeBits(runControlBits111, 3)
+		bs.writeBits(n, runNBits111)
+	}
+
+	st.run = run - 1
+}
+
+func writeStringValue(bs *bstream, st *valueState, id uint64, str string, first bool) {
+	switch {
+	case first || id >= st.dictN:
+		// '11' & length & bytes for new dictionary entry
+		bs.writeBits(valueControlBits11, 2)
+		bs.writeBits(uint64(len(str)), strLenNBits)
+		for k := 0; k < len(str); k++ {
+			bs.writeByte(str[k])
+		}
+
+		st.dictN++
+
+	case id == st.vbits:
+		bs.writeBit(zero)
+
+	default:
+		// '10' & id of an existing entry
+		bs.writeBits(valueControlBits10, 2)
+		bs.writeBits(id, dictIDNBits(st.dictN))
+	}
+}
+
+func dictIDNBits(dictN uint64) uint {
+	if dictN == 0 {
+		return 0
+	}
+
+	return uint(bits.Len64(dictN - 1))
+}
+
+// readValue read value bits of the first or following points, return the control bits
+func (i *Iter) readValue(first bool) (uint64, error) {
+	switch i.vtype {
+	case TypeInt64:
+		return readInt64Value(i.bs, &i.value, first)
+
+	case TypeBool:
+		return i.readBoolValue()
+
+	case TypeString:
+		return i.readStringValue()
+
+	default:
+		return readFloat64Value(i.bs, &i.value, first)
+	}
+}
+
+// readFloat64Value read a XOR encoded value, return the control bits
+func readFloat64Value(bs *bstream, st *valueState, first bool) (uint64, error) {
+	if first {
+		vbits, err := bs.readBits(64)
+		if err != nil {
+			return 0, fmt.Errorf("read first value bits: %s", err)
+		}
+
+		st.vbits = vbits
+		return 0, nil
+	}
+
+	valCtrlBits, err := readValueControlBits(bs)
+	if err != nil {
+		return 0, fmt.Errorf("read value control bits: %s", err)
+	}
+
+	var vdelta uint64
+
+	switch valCtrlBits {
+	case valueControlBits0:
+		// vdelta = 0
+
+	case valueControlBits10:
+		meaningfulNBits := uint(64 - st.leading - st.trailing)
+		meaningful, err := bs.readBits(meaningfulNBits)
+		if err != nil {
+			return 0, fmt.Errorf("read meaningful value with control bits %02x: %s", valCtrlBits, err)
+		}
+
+		vdelta = meaningful << st.trailing
+
+	case valueControlBits11:
+		leading, err := bs.readBits(6)
+		if err != nil {
+			return 0, fmt.Errorf("read leading bits: %s", err)
+		}
+
+		meaningfulNbits, err := bs.readBits(6)
+		if err != nil {
+			return 0, fmt.Errorf("read meaningful bits: %s", err)
+		}
+
+		// 64 meaningful bits overflows the 6 bits field to be 0
+		if meaningfulNbits == 0 {
+			meaningfulNbits = 64
+		}
+
+		meaningful, err := bs.readBits(uint(meaningfulNbits))
+		if err != nil {
+			return 0, fmt.Errorf("read meaningful value with control bits %02x: %s", valCtrlBits, err)
+		}
+
+		st.leading = uint8(leading)
+		st.trailing = uint8(64 - leading - meaningfulNbits)
+		vdelta = meaningful << st.trailing
+
+	default:
+		return 0, fmt.Errorf("malformed value control bits %02x", valCtrlBits)
+	}
+
+	st.vbits ^= vdelta
+	return valCtrlBits, nil
+}
+
+// readInt64Value read a delta-of-delta encoded value, return the control bits
+func readInt64Value(bs *bstream, st *valueState, first bool) (uint64, error) {
+	if first {
+		vbits, err := bs.readBits(64)
+		if err != nil {
+			return 0, fmt.Errorf("read first value bits: %s", err)
+		}
+
+		st.vbits = vbits
+		st.vdelta = 0
+		return 0, nil
+	}
+
+	ctrlBits, err := readDoDControlBits(bs)
+	if err != nil {
+		return 0, fmt.Errorf("read value control bits: %s", err)
+	}
+
+	var dod int64
+	if ctrlBits != dodControlBits0 {
+		dodbits, err := bs.readBits(intDoDBuckets[ctrlBits].dodNBits)
+		if err != nil {
+			return 0, fmt.Errorf("read value dod bits: %s", err)
+		}
+
+		dod = zagzig64(dodbits)
+	}
+
+	st.vdelta += dod
+	st.vbits = uint64(int64(st.vbits) + st.vdelta)
+	return ctrlBits, nil
+}
+
+// readBoolValue read the value bit & the length of a run at its first point, return 1 for it, 0 for the following points
+func (i *Iter) readBoolValue() (uint64, error) {
+	if i.value.run > 0 {
+		i.value.run--
+		return 0, nil
+	}
+
+	b, err := i.bs.readBit()
+	if err != nil {
+		return 0, fmt.Errorf("read bool value bit: %s", err)
+	}
+
+	run, err := readRunLength(i.bs)
+	if err != nil {
+		return 0, fmt.Errorf("read bool run length: %s", err)
+	}
+
+	i.value.vbits = 0
+	if b {
+		i.value.vbits = 1
+	}
+
+	i.value.run = run - 1
+	return 1, nil
+}
+
+func readRunLength(bs *bstream) (uint64, error) {
+	ctrlBits, err := bs.readControlBits(3)
+	if err != nil {
+		return 0, err
+	}
+
+	var nbits uint
+	switch ctrlBits {
+	case runControlBits10:
+		nbits = runNBits10
+
+	case runControlBits110:
+		nbits = runNBits110
+
+	case runControlBits111:
+		nbits = runNBits111
+	}
+
+	n, err := bs.readBits(nbits)
+	if err != nil {
+		return 0, err
+	}
+
+	return n + 1, nil
+}
+
+func (i *Iter) readStringValue() (uint64, error) {
+	valCtrlBits, err := readValueControlBits(i.bs)
+	if err != nil {
+		return 0, fmt.Errorf("read value control bits: %s", err)
+	}
+
+	switch valCtrlBits {
+	case valueControlBits0:
+
+	case valueControlBits10:
+		id, err := i.bs.readBits(dictIDNBits(i.value.dictN))
+		if err != nil {
+			return 0, fmt.Errorf("read dictionary id: %s", err)
+		}
+
+		if id >= i.value.dictN {
+			return 0, fmt.Errorf("malformed dictionary id %d", id)
+		}
+
+		i.value.vbits = id
+
+	case valueControlBits11:
+		size, err := i.bs.readBits(strLenNBits)
+		if err != nil {
+			return 0, fmt.Errorf("read string length: %s", err)
+		}
+
+		// entries may be known already if the iterator comes from a chunk
+		known := i.value.dictN < uint64(len(i.dict))
+
+		var buf []byte
+		if !known {
+			buf = make([]byte, 0, 16)
+		}
+
+		for k := uint64(0); k < size; k++ {
+			b, err := i.bs.readByte()
+			if err != nil {
+				return 0, fmt.Errorf("read string bytes: %s", err)
+			}
+
+			if !known {
+				buf = append(buf, b)
+			}
+		}
+
+		if !known {
+			i.dict = append(i.dict, string(buf))
+		}
+
+		i.value.vbits = i.value.dictN
+		i.value.dictN++
+
+	default:
+		return 0, fmt.Errorf("malformed value control bits %02x", valCtrlBits)
+	}
+
+	return valCtrlBits, nil
+}
+
+func writeValueState(w *bwriter, st valueState) {
+	w.write(st.vbits)
+	w.write(st.leading)
+	w.write(st.trailing)
+	w.write(st.vdelta)
+	w.write(st.dictN)
+	w.write(st.run)
+}
+
+func readValueState(r *breader) valueState {
+	var st valueState
+	r.read(&st.vbits)
+	r.read(&st.leading)
+	r.read(&st.trailing)
+	r.read(&st.vdelta)
+	r.read(&st.dictN)
+	r.read(&st.run)
+	return st
+}
@@ -0,0 +1,323 @@
+package chunk
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTypedChunk(t *testing.T) {
+	pointNum := 10000
+	baseT := time.Now().Truncate(24 * time.Hour)
+
+	floats := make([]float64, pointNum)
+	ints := make([]int64, pointNum)
+	bools := make([]bool, pointNum)
+	strs := make([]string, pointNum)
+
+	var counter int64 = math.MaxInt64 - 1000
+	for i := 0; i < pointNum; i++ {
+		floats[i] = math.Round(rand.NormFloat64()*10000) / 100
+		counter += rand.Int63n(3)
+		if i%1000 == 999 {
+			counter = math.MinInt64 + rand.Int63n(100)
+		}
+		ints[i] = counter
+		bools[i] = (i/(1+rand.Intn(20)))%2 == 0
+		strs[i] = fmt.Sprintf("state-%d", rand.Intn(1+i%7))
+		if i%500 == 0 {
+			strs[i] = ""
+		}
+	}
+
+	check := func(t *testing.T, iter *Iter, vt ValueType, ts []int64) {
+		if iter.Type() != vt {
+			t.Fatalf("expected value type %s, got %s", vt, iter.Type())
+		}
+
+		i := 0
+		for iter.Next() {
+			var pt int64
+			switch vt {
+			case TypeFloat64:
+				var v float64
+				pt, v = iter.PointFloat64()
+				if v != floats[i] {
+					t.Fatalf("#%d expected %v, got %v", i+1, floats[i], v)
+				}
+
+			case TypeInt64:
+				var v int64
+				pt, v = iter.PointInt64()
+				if v != ints[i] {
+					t.Fatalf("#%d expected %v, got %v", i+1, ints[i], v)
+				}
+
+			case TypeBool:
+				var v bool
+				pt, v = iter.PointBool()
+				if v != bools[i] {
+					t.Fatalf("#%d expected %v, got %v", i+1, bools[i], v)
+				}
+
+			case TypeString:
+				var v string
+				pt, v = iter.PointString()
+				if v != strs[i] {
+					t.Fatalf("#%d expected %q, got %q", i+1, strs[i], v)
+				}
+			}
+
+			if pt != ts[i] {
+				t.Fatalf("#%d expected timestamp %d, got %d", i+1, ts[i], pt)
+			}
+
+			i++
+		}
+
+		if err := iter.Err(); err != nil {
+			t.Fatalf("got iter err: %s", err)
+		}
+
+		if i != pointNum {
+			t.Fatalf("expected %d points, got %d", pointNum, i)
+		}
+	}
+
+	for _, vt := range []ValueType{TypeFloat64, TypeInt64, TypeBool, TypeString} {
+		t.Run(vt.String(), func(t *testing.T) {
+			ck := NewChunk(baseT, Type(vt), Checkpoints(100))
+			ts := make([]int64, pointNum)
+			tm := baseT.Unix()
+			for i := 0; i < pointNum; i++ {
+				tm += 1 + rand.Int63n(3)
+				ts[i] = tm
+
+				switch vt {
+				case TypeFloat64:
+					ck.PushFloat64(tm, floats[i])
+
+				case TypeInt64:
+					ck.PushInt64(tm, ints[i])
+
+				case TypeBool:
+					ck.PushBool(tm, bools[i])
+
+				case TypeString:
+					ck.PushString(tm, strs[i])
+				}
+			}
+
+			iter, err := ck.Iter()
+			if err != nil {
+				t.Fatalf("new iterator: %s", err)
+			}
+
+			check(t, iter, vt, ts)
+
+			// decode without being told the value type
+			ck.Finish()
+			iter, err = NewIter(ck.bs.stream, time.Second)
+			if err != nil {
+				t.Fatalf("new iterator from raw stream: %s", err)
+			}
+
+			check(t, iter, vt, ts)
+
+			data, err := ck.MarshalBinary()
+			if err != nil {
+				t.Fatalf("marshal binary %s", err)
+			}
+
+			unmarshaled := new(Chunk)
+			if err := unmarshaled.UnmarshalBinary(data); err != nil {
+				t.Fatalf("unmarshal binary %s", err)
+			}
+
+			iter, err = unmarshaled.Iter()
+			if err != nil {
+				t.Fatalf("new iterator: %s", err)
+			}
+
+			for j := 0; j < 50; j++ {
+				idx := rand.Intn(pointNum)
+				if !iter.SeekTo(ts[idx]) {
+					t.Fatalf("#%d seek %d: expected to find point", j+1, ts[idx])
+				}
+
+				var ok bool
+				switch vt {
+				case TypeFloat64:
+					_, v := iter.PointFloat64()
+					ok = v == floats[idx]
+
+				case TypeInt64:
+					_, v := iter.PointInt64()
+					ok = v == ints[idx]
+
+				case TypeBool:
+					_, v := iter.PointBool()
+					ok = v == bools[idx]
+
+				case TypeString:
+					_, v := iter.PointString()
+					ok = v == strs[idx]
+				}
+
+				if !ok {
+					t.Fatalf("#%d unexpected value after seeking to point %d", j+1, idx)
+				}
+			}
+		})
+	}
+}
+
+func TestStringDictStoredOnce(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+	long := strings.Repeat("state-", 20)
+	vals := []string{long, "b", long, "c", "b", long}
+
+	tm := baseT.Unix()
+	marshal := func(s string) []byte {
+		ck := NewChunk(baseT, Type(TypeString))
+		tm = baseT.Unix()
+		for _, v := range vals {
+			if v == long {
+				v = s
+			}
+
+			tm++
+			if err := ck.PushString(tm, v); err != nil {
+				t.Fatalf("push %q: %s", v, err)
+			}
+		}
+
+		data, err := ck.MarshalBinary()
+		if err != nil {
+			t.Fatalf("marshal binary %s", err)
+		}
+
+		return data
+	}
+
+	short, data := marshal("a"), marshal(long)
+	if grown := len(data) - len(short); grown >= 2*(len(long)-1) {
+		t.Fatalf("expected the string to be stored once, the chunk grows by %d bytes", grown)
+	}
+
+	unmarshaled := new(Chunk)
+	if err := unmarshaled.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal binary %s", err)
+	}
+
+	// the dictionary is rebuilt from the stream, known values keep their ids
+	for _, v := range []string{"d", long, "c"} {
+		tm++
+		vals = append(vals, v)
+		if err := unmarshaled.PushString(tm, v); err != nil {
+			t.Fatalf("push %q: %s", v, err)
+		}
+	}
+
+	if len(unmarshaled.dict) != 4 {
+		t.Fatalf("expected 4 dictionary entries, got %v", unmarshaled.dict)
+	}
+
+	iter, err := unmarshaled.Iter()
+	if err != nil {
+		t.Fatalf("new iterator: %s", err)
+	}
+
+	i := 0
+	for ; iter.Next(); i++ {
+		if _, v := iter.PointString(); v != vals[i] {
+			t.Fatalf("#%d expected %q, got %q", i+1, vals[i], v)
+		}
+	}
+
+	if err := iter.Err(); err != nil || i != len(vals) {
+		t.Fatalf("expected %d points, got %d, err %v", len(vals), i, err)
+	}
+}
+
+func TestBoolRuns(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+
+	// runs in every length bucket, longer ones are split
+	runs := []int{1, 1, 5, 20, 100, 256, 1000, 1, 33}
+
+	ck := NewChunk(baseT, Type(TypeBool), Checkpoints(64))
+	var ts []int64
+	var vals []uint64
+	tm := baseT.Unix()
+	for k, n := range runs {
+		for j := 0; j < n; j++ {
+			tm++
+			ts = append(ts, tm)
+			vals = append(vals, uint64(k%2))
+			if err := ck.Push(tm, uint64(k%2)*7); err != nil {
+				t.Fatalf("push %d: %s", tm, err)
+			}
+		}
+	}
+
+	// the open run is buffered & read from the buffer
+	if len(ck.buf) != runs[len(runs)-1] || ck.Num() != uint64(len(ts)) {
+		t.Fatalf("expected the last run buffered, got %d buffered of %d", len(ck.buf), ck.Num())
+	}
+
+	if err := ck.Push(tm-1, 1); err != ErrOutOfOrder {
+		t.Fatalf("expected ErrOutOfOrder, got %v", err)
+	}
+
+	if err := ck.Push(tm, 1); err != ErrDuplicatePoint {
+		t.Fatalf("expected ErrDuplicatePoint, got %v", err)
+	}
+
+	iter, err := ck.Iter()
+	if err != nil {
+		t.Fatalf("new iterator: %s", err)
+	}
+
+	checkPoints(t, iter, ts, vals)
+
+	if err := ck.Finish(); err != nil {
+		t.Fatalf("finish %s", err)
+	}
+
+	// constant timestamp deltas cost a bit per point, values at most 12 bits per run,
+	// besides the stream header & the end-of-stream record
+	if bits, max := ck.bs.writeOffset(), uint64(len(ts)+12*2*len(runs)+256); bits > max {
+		t.Fatalf("expected runs to be encoded in %d bits, got %d", max, bits)
+	}
+
+	data, err := ck.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal binary %s", err)
+	}
+
+	unmarshaled := new(Chunk)
+	if err := unmarshaled.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal binary %s", err)
+	}
+
+	iter, err = unmarshaled.Iter()
+	if err != nil {
+		t.Fatalf("new iterator: %s", err)
+	}
+
+	// checkpoints fall in the middle of runs
+	for j := 0; j < 50; j++ {
+		idx := rand.Intn(len(ts))
+		if !iter.SeekTo(ts[idx]) {
+			t.Fatalf("#%d seek %d: expected to find point", j+1, ts[idx])
+		}
+
+		if pt, pv := iter.Point(); pt != ts[idx] || pv != vals[idx] {
+			t.Fatalf("#%d unexpected point (%d, %d) after seeking to %d", j+1, pt, pv, idx)
+		}
+	}
+}
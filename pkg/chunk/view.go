@@ -0,0 +1,102 @@
+package chunk
+
+// view an immutable snapshot of the chunk, published when a reader asks for it after a write,
+// so that readers can iterate the stream up to the recorded position
+// without taking the lock or copying the stream.
+//
+// the encoder only ever modifies the last byte of the stream, the header word and bytes
+// appended after it, so the view keeps a copy of the last byte and readers skip the header word.
+type view struct {
+	stream []byte
+	last   byte
+	wBit   uint8
+
+	num      uint64
+	dict     []string
+	cps      []checkpoint
+	finished bool
+
+	// number of buffered points, which have to be copied under the read lock
+	nbuf int
+}
+
+// invalidate drop the published view after a write, must be called with the write lock held.
+// writers do not build views, so pushing points does not allocate
+func (c *Chunk) invalidate() {
+	if v, _ := c.view.Load().(*view); v != nil {
+		c.view.Store((*view)(nil))
+	}
+}
+
+// publish return the published view, or record the current write position,
+// must be called with the read or write lock held
+func (c *Chunk) publish() *view {
+	if v, _ := c.view.Load().(*view); v != nil {
+		return v
+	}
+
+	v := &view{
+		stream:   c.bs.stream,
+		wBit:     c.bs.wBit,
+		num:      c.num,
+		dict:     c.dict[:len(c.dict):len(c.dict)],
+		cps:      c.cps[:len(c.cps):len(c.cps)],
+		finished: c.finished,
+		nbuf:     len(c.buf),
+	}
+
+	if n := len(v.stream); n > 0 {
+		v.last = v.stream[n-1]
+	}
+
+	// concurrent readers holding the read lock store equivalent views
+	c.view.Store(v)
+	return v
+}
+
+func (c *Chunk) loadView() *view {
+	if v, _ := c.view.Load().(*view); v != nil {
+		return v
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.publish()
+}
+
+// buffered return a copy of the buffered points, string values are given ids
+// following the dictionary, which is extended if necessary.
+// must be called with the read lock held
+func (c *Chunk) buffered(dict []string) ([]point, []string) {
+	points := make([]point, len(c.buf))
+	copy(points, c.buf)
+
+	if c.vtype != TypeString {
+		return points, dict
+	}
+
+	var added map[string]uint64
+	for k, p := range points {
+		if id, ok := c.dictIdx[p.str]; ok && id < uint64(len(dict)) {
+			points[k].vbits = id
+			continue
+		}
+
+		if added == nil {
+			added = map[string]uint64{}
+		}
+
+		id, ok := added[p.str]
+		if !ok {
+			// dict is capped, appending copies it
+			id = uint64(len(dict))
+			dict = append(dict, p.str)
+			added[p.str] = id
+		}
+
+		points[k].vbits = id
+	}
+
+	return points, dict
+}
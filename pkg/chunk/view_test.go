@@ -0,0 +1,104 @@
+package chunk
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentIter(t *testing.T) {
+	baseT := time.Now().Truncate(24 * time.Hour)
+	t0 := baseT.Unix()
+
+	cases := []struct {
+		name string
+		str  bool
+		opts []Option
+	}{
+		{"plain", false, nil},
+		{"lag", false, []Option{Lag(5 * time.Second)}},
+		{"string", true, []Option{Type(TypeString), Order(PolicyLastWriteWins)}},
+	}
+
+	for _, cs := range cases {
+		ck := NewChunk(baseT, cs.opts...)
+		str := cs.str
+
+		var wg sync.WaitGroup
+		done := make(chan struct{})
+
+		for r := 0; r < 4; r++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for {
+					select {
+					case <-done:
+						return
+					default:
+					}
+
+					iter, err := ck.Iter()
+					if err != nil {
+						t.Errorf("%s: new iter %s", cs.name, err)
+						return
+					}
+
+					var n uint64
+					for iter.Next() {
+						ts, v := iter.Point()
+						if ts != t0+int64(n) {
+							t.Errorf("%s: #%d expected ts %d, got %d", cs.name, n, t0+int64(n), ts)
+							return
+						}
+
+						if str {
+							if _, s := iter.PointString(); s != string(rune('a'+n%26)) {
+								t.Errorf("%s: #%d unexpected value %q", cs.name, n, s)
+								return
+							}
+						} else if v != n {
+							t.Errorf("%s: #%d expected value %d, got %d", cs.name, n, n, v)
+							return
+						}
+
+						n++
+					}
+
+					if err := iter.Err(); err != nil {
+						t.Errorf("%s: iter %s", cs.name, err)
+						return
+					}
+
+					if n != iter.Total() {
+						t.Errorf("%s: expected %d points, got %d", cs.name, iter.Total(), n)
+						return
+					}
+				}
+			}()
+		}
+
+		for i := int64(0); i < 5000; i++ {
+			var err error
+			if str {
+				err = ck.PushString(t0+i, string(rune('a'+i%26)))
+			} else {
+				err = ck.Push(t0+i, uint64(i))
+			}
+
+			if err != nil {
+				t.Fatalf("%s: push %s", cs.name, err)
+			}
+		}
+
+		ck.Finish()
+		close(done)
+		wg.Wait()
+
+		iter, _ := ck.Iter()
+		if iter.Total() != 5000 {
+			t.Fatalf("%s: expected 5000 points, got %d", cs.name, iter.Total())
+		}
+	}
+}
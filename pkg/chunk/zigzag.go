@@ -17,3 +17,21 @@ func zagzig(ui uint32) int32 {
 
 	return i
 }
+
+func zigzag64(i int64) uint64 {
+	ui := uint64(i << 1)
+	if i < 0 {
+		ui = ^ui
+	}
+
+	return ui
+}
+
+func zagzig64(ui uint64) int64 {
+	i := int64(ui >> 1)
+	if ui&1 != 0 {
+		i = ^i
+	}
+
+	return i
+}
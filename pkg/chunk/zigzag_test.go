@@ -40,3 +40,27 @@ func TestZigZagBitSize(t *testing.T) {
 		}
 	}
 }
+
+func TestZigZag64(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		pos := rand.Int63n(math.MaxInt64)
+		neg := -pos
+
+		posv := zagzig64(zigzag64(pos))
+		negv := zagzig64(zigzag64(neg))
+
+		if pos != posv {
+			t.Errorf("#%d expected positive value %d, got %d", i+1, pos, posv)
+		}
+
+		if neg != negv {
+			t.Errorf("#%d expected negative value %d, got %d", i+1, neg, negv)
+		}
+	}
+
+	for i := int32(-1000); i < 1000; i++ {
+		if uint64(zigzag(i)) != zigzag64(int64(i)) {
+			t.Fatalf("expected zigzag64 compatible with zigzag for %d", i)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package series
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dtynn/winston/pkg/storage"
+	"github.com/dtynn/winston/pkg/storage/key"
+)
+
+var (
+	// ErrMalformedMatcher the matcher can not be parsed
+	ErrMalformedMatcher = errors.New("malformed matcher")
+)
+
+// MetricKey the tag key the metric name is indexed under
+const MetricKey = "__name__"
+
+// MatchType how a matcher compares tag values
+type MatchType uint8
+
+const (
+	// MatchEqual key=value
+	MatchEqual MatchType = iota
+	// MatchNotEqual key!=value
+	MatchNotEqual
+	// MatchRegexp key=~regexp, the regexp is anchored at both ends
+	MatchRegexp
+	// MatchNotRegexp key!~regexp
+	MatchNotRegexp
+)
+
+var matchOps = [...]string{
+	MatchEqual:     "=",
+	MatchNotEqual:  "!=",
+	MatchRegexp:    "=~",
+	MatchNotRegexp: "!~",
+}
+
+func (t MatchType) String() string {
+	if int(t) < len(matchOps) {
+		return matchOps[t]
+	}
+
+	return fmt.Sprintf("MatchType(%d)", uint8(t))
+}
+
+// Matcher select series by the value of a tag,
+// a series without the tag has an empty value
+type Matcher struct {
+	Type  MatchType
+	Key   string
+	Value string
+
+	re *regexp.Regexp
+}
+
+// NewMatcher return a matcher, regexps are compiled
+func NewMatcher(t MatchType, key, value string) (*Matcher, error) {
+	m := &Matcher{
+		Type:  t,
+		Key:   key,
+		Value: value,
+	}
+
+	switch t {
+	case MatchEqual, MatchNotEqual:
+
+	case MatchRegexp, MatchNotRegexp:
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, err
+		}
+
+		m.re = re
+
+	default:
+		return nil, ErrMalformedMatcher
+	}
+
+	return m, nil
+}
+
+// ParseMatcher parse a matcher like host=web-1, region!=us, env=~prod.* or env!~dev.*
+func ParseMatcher(s string) (*Matcher, error) {
+	idx := strings.IndexAny(s, "=!")
+	if idx <= 0 {
+		return nil, ErrMalformedMatcher
+	}
+
+	key, rest := s[:idx], s[idx:]
+	for _, t := range []MatchType{MatchNotEqual, MatchRegexp, MatchNotRegexp, MatchEqual} {
+		if strings.HasPrefix(rest, matchOps[t]) {
+			return NewMatcher(t, key, rest[len(matchOps[t]):])
+		}
+	}
+
+	return nil, ErrMalformedMatcher
+}
+
+func (m *Matcher) String() string {
+	return m.Key + m.Type.String() + m.Value
+}
+
+// Matches check the value of the tag
+func (m *Matcher) Matches(value string) bool {
+	switch m.Type {
+	case MatchEqual:
+		return value == m.Value
+
+	case MatchNotEqual:
+		return value != m.Value
+
+	case MatchRegexp:
+		return m.re.MatchString(value)
+
+	default:
+		return !m.re.MatchString(value)
+	}
+}
+
+// negative matchers are evaluated as the complement of the positive ones
+func (m *Matcher) negative() bool {
+	return m.Type == MatchNotEqual || m.Type == MatchNotRegexp
+}
+
+// terms of the index, the posting list of a term is split into blocks, see postings.go:
+//
+//	post | key size uvarint | key | escaped value                -> open block
+//	post | key size uvarint | key | escaped value | first id(8) -> sealed block
+//
+// new ids are appended to the open block, which is sealed under its first id once it reaches maxBlockSize,
+// so an Assign rewrites a bounded block per term. sealed blocks are scanned in order, before the open one.
+// values are escaped & terminated, see key.Str,
+// so values with a common prefix can be scanned with PrefixIterator over the escaped prefix.
+// the term with empty key & value holds all series.
+var postPrefix = []byte("post/")
+
+// maxBlockSize the size an open block is sealed at
+const maxBlockSize = 1 << 10
+
+func (st *Store) termPrefix(name string) []byte {
+	buf := bytes.NewBuffer(st.key(postPrefix))
+	writeField(buf, name)
+	return buf.Bytes()
+}
+
+func (st *Store) termKey(name, value string) []byte {
+	return key.Key(st.termPrefix(name), key.Str(value))
+}
+
+func (st *Store) allKey() []byte {
+	return st.termKey("", "")
+}
+
+// terms return the keys of the posting lists the series belongs to
+func (st *Store) terms(s Series) [][]byte {
+	terms := [][]byte{st.allKey(), st.termKey(MetricKey, s.Metric)}
+	for _, t := range s.Tags {
+		if t.Key != "" && t.Key != MetricKey {
+			terms = append(terms, st.termKey(t.Key, t.Value))
+		}
+	}
+
+	return terms
+}
+
+// appendTerm append the id to the open block of the term, sealing the block once it is full
+func appendTerm(txn storage.Txn, term []byte, id uint64) error {
+	val, err := txn.Get(term)
+	if err != nil {
+		return err
+	}
+
+	if val, err = appendPosting(val, id); err != nil {
+		return err
+	}
+
+	if len(val) < maxBlockSize {
+		return txn.Put(term, val)
+	}
+
+	first, err := firstPosting(val)
+	if err != nil {
+		return err
+	}
+
+	if err := txn.Put(key.Key(term, key.UI64(first)), val); err != nil {
+		return err
+	}
+
+	return txn.Del(term)
+}
+
+// postings return the posting list of the term
+func (st *Store) postings(term []byte) (Postings, error) {
+	iter, err := st.s.PrefixIterator(term)
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	var res, open Postings
+	for iter.First(); iter.Valid(); iter.Next() {
+		p, err := decodePostings(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+
+		switch len(iter.Key()) - len(term) {
+		case 0:
+			open = p
+
+		case 8:
+			res = append(res, p...)
+
+		default:
+			return nil, ErrMalformedPostings
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return append(res, open...), nil
+}
+
+// scan return the union of the posting lists of the tag key whose values match fn,
+// values are scanned from the literal prefix
+func (st *Store) scan(name, prefix string, fn func(value string) bool) (Postings, error) {
+	tprefix := st.termPrefix(name)
+
+	// the escaped prefix without the terminator
+	escaped := key.Str(prefix).Bytes()
+	iter, err := st.s.PrefixIterator(append(tprefix[:len(tprefix):len(tprefix)], escaped[:len(escaped)-2]...))
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	var res Postings
+	var last string
+	matched, started := false, false
+	for iter.First(); iter.Valid(); iter.Next() {
+		rest := iter.Key()[len(tprefix):]
+		n, err := key.Str("").EncodedSize(rest)
+		if err != nil || (len(rest) != n && len(rest) != n+8) {
+			return nil, ErrMalformedPostings
+		}
+
+		var value key.Str
+		if err := value.UnmarshalBinary(rest[:n]); err != nil {
+			return nil, ErrMalformedPostings
+		}
+
+		// blocks of a value are adjacent
+		if !started || string(value) != last {
+			last, matched, started = string(value), fn(string(value)), true
+		}
+
+		if !matched {
+			continue
+		}
+
+		p, err := decodePostings(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, p...)
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	// a series has one value of the tag key, so the ids are distinct
+	sort.Slice(res, func(i, j int) bool {
+		return res[i] < res[j]
+	})
+
+	return res, nil
+}
+
+// match return the series whose tag value matches the positive form of the matcher
+func (st *Store) match(m *Matcher) (Postings, error) {
+	var value string
+	var re *regexp.Regexp
+
+	switch m.Type {
+	case MatchEqual, MatchNotEqual:
+		value = m.Value
+
+	default:
+		re = m.re
+	}
+
+	// the empty value matches series without the tag
+	if (re == nil && value == "") || (re != nil && re.MatchString("")) {
+		with, err := st.scan(m.Key, "", func(v string) bool {
+			return !(re == nil && v == value) && !(re != nil && re.MatchString(v))
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		all, err := st.postings(st.allKey())
+		if err != nil {
+			return nil, err
+		}
+
+		return Without(all, with), nil
+	}
+
+	if re == nil {
+		return st.postings(st.termKey(m.Key, value))
+	}
+
+	prefix, _ := re.LiteralPrefix()
+	return st.scan(m.Key, prefix, re.MatchString)
+}
+
+// Select return the ids of series matching all the matchers, all series if no matcher is given
+func (st *Store) Select(matchers ...*Matcher) (Postings, error) {
+	var res Postings
+	positive := false
+
+	for _, m := range matchers {
+		if m.negative() {
+			continue
+		}
+
+		p, err := st.match(m)
+		if err != nil {
+			return nil, err
+		}
+
+		if positive {
+			res = Intersect(res, p)
+		} else {
+			res, positive = p, true
+		}
+	}
+
+	if !positive {
+		all, err := st.postings(st.allKey())
+		if err != nil {
+			return nil, err
+		}
+
+		res = all
+	}
+
+	for _, m := range matchers {
+		if !m.negative() || len(res) == 0 {
+			continue
+		}
+
+		p, err := st.match(m)
+		if err != nil {
+			return nil, err
+		}
+
+		res = Without(res, p)
+	}
+
+	return res, nil
+}
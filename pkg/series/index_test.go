@@ -0,0 +1,233 @@
+package series
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dtynn/winston/pkg/storage"
+)
+
+func TestPostings(t *testing.T) {
+	a := Postings{1, 3, 5, 7, 9}
+	b := Postings{2, 3, 4, 9, 10}
+
+	if got := Intersect(a, b); !reflect.DeepEqual(got, Postings{3, 9}) {
+		t.Fatalf("unexpected intersection %v", got)
+	}
+
+	if got := Union(a, b); !reflect.DeepEqual(got, Postings{1, 2, 3, 4, 5, 7, 9, 10}) {
+		t.Fatalf("unexpected union %v", got)
+	}
+
+	if got := Without(a, b); !reflect.DeepEqual(got, Postings{1, 5, 7}) {
+		t.Fatalf("unexpected difference %v", got)
+	}
+
+	var data []byte
+	var err error
+	for _, id := range a {
+		if data, err = appendPosting(data, id); err != nil {
+			t.Fatalf("append %d: %s", id, err)
+		}
+	}
+
+	if _, err := appendPosting(data, 9); err != ErrMalformedPostings {
+		t.Fatalf("expected ErrMalformedPostings for a non increasing id, got %v", err)
+	}
+
+	p, err := decodePostings(data)
+	if err != nil {
+		t.Fatalf("decode %s", err)
+	}
+
+	if !reflect.DeepEqual(p, a) {
+		t.Fatalf("expected %v, got %v", a, p)
+	}
+
+	if _, err := decodePostings(data[:len(data)-1]); err != ErrMalformedPostings {
+		t.Fatalf("expected ErrMalformedPostings for a truncated list, got %v", err)
+	}
+
+	if first, err := firstPosting(data); err != nil || first != a[0] {
+		t.Fatalf("expected first id %d, got %d, %v", a[0], first, err)
+	}
+}
+
+func TestParseMatcher(t *testing.T) {
+	cases := []struct {
+		s     string
+		typ   MatchType
+		key   string
+		value string
+	}{
+		{"host=web-1", MatchEqual, "host", "web-1"},
+		{"region!=us", MatchNotEqual, "region", "us"},
+		{"env=~prod.*", MatchRegexp, "env", "prod.*"},
+		{"env!~dev.*", MatchNotRegexp, "env", "dev.*"},
+		{"env=", MatchEqual, "env", ""},
+		{"expr==1", MatchEqual, "expr", "=1"},
+	}
+
+	for _, c := range cases {
+		m, err := ParseMatcher(c.s)
+		if err != nil {
+			t.Fatalf("parse %q: %s", c.s, err)
+		}
+
+		if m.Type != c.typ || m.Key != c.key || m.Value != c.value {
+			t.Fatalf("unexpected matcher %q parsed from %q", m, c.s)
+		}
+
+		if m.String() != c.s {
+			t.Fatalf("expected %q, got %q", c.s, m)
+		}
+	}
+
+	for _, s := range []string{"", "host", "=a", "host!a"} {
+		if _, err := ParseMatcher(s); err != ErrMalformedMatcher {
+			t.Fatalf("expected ErrMalformedMatcher for %q, got %v", s, err)
+		}
+	}
+
+	if _, err := ParseMatcher("env=~("); err == nil {
+		t.Fatal("expected error for invalid regexp")
+	}
+
+	m, _ := ParseMatcher("env=~prod")
+	if m.Matches("production") {
+		t.Fatal("expected the regexp to be anchored")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	testStorages(t, func(t *testing.T, s storage.Storage) {
+		st := NewStore(s, []byte("_series_"))
+
+		all := []Series{
+			New("cpu", map[string]string{"host": "web-1", "env": "prod"}),
+			New("cpu", map[string]string{"host": "web-2", "env": "production"}),
+			New("cpu", map[string]string{"host": "db-1", "env": "dev"}),
+			New("mem", map[string]string{"host": "web-1"}),
+			New("mem", map[string]string{"host": "db-1", "env": "prod"}),
+		}
+
+		for i, s := range all {
+			id, err := st.Assign(s)
+			if err != nil {
+				t.Fatalf("assign %s", err)
+			}
+
+			if id != uint64(i+1) {
+				t.Fatalf("expected id %d, got %d", i+1, id)
+			}
+		}
+
+		// assigning a known series does not touch the index
+		if _, err := st.Assign(all[0]); err != nil {
+			t.Fatalf("assign %s", err)
+		}
+
+		cases := []struct {
+			matchers []string
+			expected Postings
+		}{
+			{nil, Postings{1, 2, 3, 4, 5}},
+			{[]string{"__name__=cpu"}, Postings{1, 2, 3}},
+			{[]string{"host=web-1"}, Postings{1, 4}},
+			{[]string{"__name__=cpu", "host=web-1"}, Postings{1}},
+			{[]string{"host!=web-1"}, Postings{2, 3, 5}},
+			{[]string{"env=~prod.*"}, Postings{1, 2, 5}},
+			{[]string{"env=~prod"}, Postings{1, 5}},
+			{[]string{"env!~prod.*"}, Postings{3, 4}},
+			{[]string{"host=~web-.*|db-1", "env!=prod"}, Postings{2, 3, 4}},
+			{[]string{"env="}, Postings{4}},
+			{[]string{"env!="}, Postings{1, 2, 3, 5}},
+			{[]string{"env=~|dev"}, Postings{3, 4}},
+			{[]string{"__name__=disk"}, nil},
+			{[]string{"zone=a"}, nil},
+			{[]string{"zone!=a"}, Postings{1, 2, 3, 4, 5}},
+		}
+
+		for _, c := range cases {
+			matchers := make([]*Matcher, 0, len(c.matchers))
+			for _, s := range c.matchers {
+				m, err := ParseMatcher(s)
+				if err != nil {
+					t.Fatalf("parse %q: %s", s, err)
+				}
+
+				matchers = append(matchers, m)
+			}
+
+			got, err := st.Select(matchers...)
+			if err != nil {
+				t.Fatalf("select %v: %s", c.matchers, err)
+			}
+
+			if len(got) != len(c.expected) || (len(got) > 0 && !reflect.DeepEqual(got, c.expected)) {
+				t.Fatalf("select %v: expected %v, got %v", c.matchers, c.expected, got)
+			}
+		}
+	})
+}
+
+func TestAssignPostingBlocks(t *testing.T) {
+	testStorages(t, func(t *testing.T, s storage.Storage) {
+		st := NewStore(s, []byte("_series_"))
+
+		num := 3000
+		for i := 0; i < num; i++ {
+			if _, err := st.Assign(New("cpu", map[string]string{"host": fmt.Sprint("web-", i)})); err != nil {
+				t.Fatalf("assign %s", err)
+			}
+		}
+
+		// blocks are bounded, so assigning does not rewrite whole posting lists
+		term := st.termKey(MetricKey, "cpu")
+		iter, err := s.PrefixIterator(term)
+		if err != nil {
+			t.Fatalf("iterator %s", err)
+		}
+
+		defer iter.Close()
+
+		blocks := 0
+		for iter.First(); iter.Valid(); iter.Next() {
+			if size := len(iter.Value()); size >= maxBlockSize+binary.MaxVarintLen64 {
+				t.Fatalf("unexpected block size %d", size)
+			}
+
+			blocks++
+		}
+
+		if blocks < 2 {
+			t.Fatalf("expected the posting list to be split, got %d blocks", blocks)
+		}
+
+		p, err := st.postings(term)
+		if err != nil {
+			t.Fatalf("postings %s", err)
+		}
+
+		for i, id := range p {
+			if id != uint64(i+1) {
+				t.Fatalf("expected id %d at %d, got %d", i+1, i, id)
+			}
+		}
+
+		if len(p) != num {
+			t.Fatalf("expected %d ids, got %d", num, len(p))
+		}
+
+		got, err := st.scan("host", "web-", func(string) bool { return true })
+		if err != nil {
+			t.Fatalf("scan %s", err)
+		}
+
+		if !reflect.DeepEqual(got, p) {
+			t.Fatalf("expected all ids from the scan, got %d", len(got))
+		}
+	})
+}
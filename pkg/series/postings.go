@@ -0,0 +1,159 @@
+package series
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	// ErrMalformedPostings the stored posting list is malformed
+	ErrMalformedPostings = errors.New("malformed posting list")
+)
+
+// Postings sorted series ids
+type Postings []uint64
+
+// Intersect return ids in both a & b
+func Intersect(a, b Postings) Postings {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+
+	res := make(Postings, 0, n)
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+
+		case a[i] > b[j]:
+			j++
+
+		default:
+			res = append(res, a[i])
+			i++
+			j++
+		}
+	}
+
+	return res
+}
+
+// Union return ids in a or b
+func Union(a, b Postings) Postings {
+	res := make(Postings, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			res = append(res, a[i])
+			i++
+
+		case a[i] > b[j]:
+			res = append(res, b[j])
+			j++
+
+		default:
+			res = append(res, a[i])
+			i++
+			j++
+		}
+	}
+
+	res = append(res, a[i:]...)
+	return append(res, b[j:]...)
+}
+
+// Without return ids in a but not in b
+func Without(a, b Postings) Postings {
+	res := make(Postings, 0, len(a))
+	j := 0
+	for _, id := range a {
+		for j < len(b) && b[j] < id {
+			j++
+		}
+
+		if j < len(b) && b[j] == id {
+			continue
+		}
+
+		res = append(res, id)
+	}
+
+	return res
+}
+
+// posting block layout:
+//
+//	last id(8) | uvarint delta from the previous id *
+//
+// the first delta is from 0. ids are assigned in increasing order,
+// so a new id is appended without decoding the block.
+
+// appendPosting append an id greater than all ids in the block
+func appendPosting(data []byte, id uint64) ([]byte, error) {
+	var last uint64
+	if len(data) == 0 {
+		data = make([]byte, 8, 8+binary.MaxVarintLen64)
+	} else {
+		if len(data) < 8 {
+			return nil, ErrMalformedPostings
+		}
+
+		last = binary.BigEndian.Uint64(data)
+		if id <= last {
+			return nil, ErrMalformedPostings
+		}
+
+		data = append([]byte(nil), data...)
+	}
+
+	binary.BigEndian.PutUint64(data, id)
+	return binary.AppendUvarint(data, id-last), nil
+}
+
+func decodePostings(data []byte) (Postings, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if len(data) < 8 {
+		return nil, ErrMalformedPostings
+	}
+
+	last := binary.BigEndian.Uint64(data)
+	data = data[8:]
+
+	var p Postings
+	var id uint64
+	for len(data) > 0 {
+		delta, n := binary.Uvarint(data)
+		if n <= 0 || delta == 0 {
+			return nil, ErrMalformedPostings
+		}
+
+		id += delta
+		p = append(p, id)
+		data = data[n:]
+	}
+
+	if id != last {
+		return nil, ErrMalformedPostings
+	}
+
+	return p, nil
+}
+
+// firstPosting return the first id of a non empty block
+func firstPosting(data []byte) (uint64, error) {
+	if len(data) < 8 {
+		return 0, ErrMalformedPostings
+	}
+
+	id, n := binary.Uvarint(data[8:])
+	if n <= 0 || id == 0 {
+		return 0, ErrMalformedPostings
+	}
+
+	return id, nil
+}
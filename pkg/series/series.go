@@ -0,0 +1,123 @@
+// Package series stores chunks of time series identified by metric name & tags on top of storage.Storage.
+package series
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+var (
+	// ErrEmptyMetric the metric name is empty
+	ErrEmptyMetric = errors.New("empty metric name")
+
+	// ErrMalformedSeries the stored series is malformed
+	ErrMalformedSeries = errors.New("malformed series")
+)
+
+// Tag a tag of the series
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Series metric name & tags, which identify a time series
+type Series struct {
+	Metric string
+	Tags   []Tag
+}
+
+// New return a series with tags sorted by key
+func New(metric string, tags map[string]string) Series {
+	s := Series{
+		Metric: metric,
+		Tags:   make([]Tag, 0, len(tags)),
+	}
+
+	for k, v := range tags {
+		s.Tags = append(s.Tags, Tag{
+			Key:   k,
+			Value: v,
+		})
+	}
+
+	sort.Slice(s.Tags, func(i, j int) bool {
+		return s.Tags[i].Key < s.Tags[j].Key
+	})
+
+	return s
+}
+
+// Bytes return the canonical form of the series, fields are length prefixed, tags sorted by key
+func (s Series) Bytes() []byte {
+	tags := s.Tags
+	if !sort.SliceIsSorted(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key }) {
+		tags = append([]Tag(nil), tags...)
+		sort.Slice(tags, func(i, j int) bool {
+			return tags[i].Key < tags[j].Key
+		})
+	}
+
+	buf := new(bytes.Buffer)
+	writeField(buf, s.Metric)
+	for _, t := range tags {
+		writeField(buf, t.Key)
+		writeField(buf, t.Value)
+	}
+
+	return buf.Bytes()
+}
+
+// UnmarshalBinary impl encoding.BinaryUnmarshaler
+func (s *Series) UnmarshalBinary(data []byte) error {
+	metric, data, ok := readField(data)
+	if !ok {
+		return ErrMalformedSeries
+	}
+
+	s.Metric = metric
+	s.Tags = nil
+
+	for len(data) > 0 {
+		var t Tag
+		if t.Key, data, ok = readField(data); !ok {
+			return ErrMalformedSeries
+		}
+
+		if t.Value, data, ok = readField(data); !ok {
+			return ErrMalformedSeries
+		}
+
+		s.Tags = append(s.Tags, t)
+	}
+
+	return nil
+}
+
+// Get return the value of the tag
+func (s Series) Get(key string) (string, bool) {
+	for _, t := range s.Tags {
+		if t.Key == key {
+			return t.Value, true
+		}
+	}
+
+	return "", false
+}
+
+func writeField(buf *bytes.Buffer, f string) {
+	var size [binary.MaxVarintLen64]byte
+	buf.Write(size[:binary.PutUvarint(size[:], uint64(len(f)))])
+	buf.WriteString(f)
+}
+
+func readField(data []byte) (string, []byte, bool) {
+	size, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < size {
+		return "", nil, false
+	}
+
+	data = data[n:]
+	return string(data[:size]), data[size:], true
+}
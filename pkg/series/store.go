@@ -0,0 +1,286 @@
+package series
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dtynn/winston/pkg/chunk"
+	"github.com/dtynn/winston/pkg/storage"
+	"github.com/dtynn/winston/pkg/storage/key"
+)
+
+var (
+	// ErrSeriesNotFound no series with the id
+	ErrSeriesNotFound = errors.New("series not found")
+
+	// ErrChunkNotFinished only finished chunks are written
+	ErrChunkNotFinished = errors.New("chunk not finished")
+)
+
+// keys under the prefix of the store:
+//
+//	seq                          -> last assigned series id
+//	id    | series bytes         -> series id
+//	meta  | series id(8)         -> series bytes
+//	chunk | series id(8) | block start(8) -> serialized chunk
+//	post  | tag key | escaped tag value               -> open posting block, see index.go
+//	post  | tag key | escaped tag value | first id(8) -> sealed posting block
+//
+// block starts are unix nanoseconds with the sign bit flipped, so blocks before the epoch sort first.
+var (
+	seqKey      = []byte("seq")
+	idPrefix    = []byte("id/")
+	metaPrefix  = []byte("meta/")
+	chunkPrefix = []byte("chunk/")
+)
+
+// NewStore return a series store keeping its keys under prefix
+func NewStore(s storage.Storage, prefix []byte) *Store {
+	return &Store{
+		s:      s,
+		prefix: prefix,
+	}
+}
+
+// Store series ids & chunks in a storage.
+// ids are assigned in a transaction of the storage
+type Store struct {
+	s      storage.Storage
+	prefix []byte
+}
+
+func (st *Store) key(parts ...[]byte) []byte {
+	k := append([]byte(nil), st.prefix...)
+	for _, p := range parts {
+		k = append(k, p...)
+	}
+
+	return k
+}
+
+func (st *Store) chunkKey(id uint64, start int64) []byte {
+	sid, sstart := key.UI64(id), key.SI64(start)
+	return key.Key(st.key(chunkPrefix), key.FixedFormatters{&sid, &sstart})
+}
+
+func (st *Store) seriesChunkPrefix(id uint64) []byte {
+	return key.Key(st.key(chunkPrefix), key.UI64(id))
+}
+
+// Lookup return the id of the series, false if it has not been assigned
+func (st *Store) Lookup(s Series) (uint64, bool, error) {
+	val, err := st.s.Get(st.key(idPrefix, s.Bytes()))
+	if err != nil || val == nil {
+		return 0, false, err
+	}
+
+	var id key.UI64
+	if err := id.UnmarshalBinary(val); err != nil {
+		return 0, false, err
+	}
+
+	return uint64(id), true, nil
+}
+
+// Assign return the id of the series, a new one is assigned if the series is unknown.
+// ids start from 1
+func (st *Store) Assign(s Series) (uint64, error) {
+	if s.Metric == "" {
+		return 0, ErrEmptyMetric
+	}
+
+	if id, ok, err := st.Lookup(s); err != nil || ok {
+		return id, err
+	}
+
+	var id uint64
+	err := st.s.Update(func(txn storage.Txn) error {
+		// the series may be assigned after the lookup
+		val, err := txn.Get(st.key(idPrefix, s.Bytes()))
+		if err != nil {
+			return err
+		}
+
+		if val != nil {
+			var sid key.UI64
+			if err := sid.UnmarshalBinary(val); err != nil {
+				return err
+			}
+
+			id = uint64(sid)
+			return nil
+		}
+
+		if id, err = storage.Increment(txn, st.key(seqKey), 1); err != nil {
+			return err
+		}
+
+		sbytes := s.Bytes()
+		for _, kv := range [][2][]byte{
+			{st.key(idPrefix, sbytes), key.UI64(id).Bytes()},
+			{key.Key(st.key(metaPrefix), key.UI64(id)), sbytes},
+		} {
+			if err := txn.Put(kv[0], kv[1]); err != nil {
+				return err
+			}
+		}
+
+		for _, term := range st.terms(s) {
+			if err := appendTerm(txn, term, id); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return id, err
+}
+
+// Series return the series of the id
+func (st *Store) Series(id uint64) (Series, error) {
+	var s Series
+
+	val, err := st.s.Get(key.Key(st.key(metaPrefix), key.UI64(id)))
+	if err != nil {
+		return s, err
+	}
+
+	if val == nil {
+		return s, ErrSeriesNotFound
+	}
+
+	err = s.UnmarshalBinary(val)
+	return s, err
+}
+
+// WriteChunk write a finished chunk of the series, keyed by its start time.
+// a chunk with the same start is overwritten
+func (st *Store) WriteChunk(id uint64, c *chunk.Chunk) error {
+	if !c.Finished() {
+		return ErrChunkNotFinished
+	}
+
+	data, err := c.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	start := c.Start() * int64(c.Precision())
+	return st.s.Put(st.chunkKey(id, start), data)
+}
+
+// Blocks return the start times of the chunks of the series in order
+func (st *Store) Blocks(id uint64) ([]time.Time, error) {
+	iter, err := st.s.PrefixIterator(st.seriesChunkPrefix(id))
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	var starts []time.Time
+	for iter.First(); iter.Valid(); iter.Next() {
+		start, err := st.blockStart(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+
+		starts = append(starts, time.Unix(0, start))
+	}
+
+	return starts, iter.Err()
+}
+
+func (st *Store) blockStart(k []byte) (int64, error) {
+	var id key.UI64
+	var start key.SI64
+	if err := key.Unmarshal(k, st.key(chunkPrefix), key.FixedFormatters{&id, &start}); err != nil {
+		return 0, err
+	}
+
+	return int64(start), nil
+}
+
+// Chunks return the chunks of the series holding points in [since, until),
+// zero since or until leaves the range open
+func (st *Store) Chunks(id uint64, since, until time.Time) ([]*chunk.Chunk, error) {
+	prefix := st.seriesChunkPrefix(id)
+
+	start, end := prefix, storage.PrefixEnd(prefix)
+	if !since.IsZero() {
+		start = st.chunkKey(id, since.UnixNano())
+	}
+
+	if !until.IsZero() {
+		end = st.chunkKey(id, until.UnixNano())
+	}
+
+	var chunks []*chunk.Chunk
+
+	// the block starting before since may hold points after it
+	if !since.IsZero() {
+		c, err := st.lastBefore(prefix, start, since.UnixNano())
+		if err != nil {
+			return nil, err
+		}
+
+		if c != nil {
+			chunks = append(chunks, c)
+		}
+	}
+
+	iter, err := st.s.RangeIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		c, err := decodeChunk(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+
+		chunks = append(chunks, c)
+	}
+
+	return chunks, iter.Err()
+}
+
+// lastBefore return the last chunk in [prefix, start) if it holds points at or after since
+func (st *Store) lastBefore(prefix, start []byte, since int64) (*chunk.Chunk, error) {
+	iter, err := st.s.RangeIterator(prefix, start)
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	iter.Last()
+	if !iter.Valid() {
+		return nil, iter.Err()
+	}
+
+	val := iter.Value()
+	h, err := chunk.ReadHeader(val)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.Num == 0 || h.MaxTime*int64(h.Precision) < since {
+		return nil, nil
+	}
+
+	return decodeChunk(val)
+}
+
+func decodeChunk(data []byte) (*chunk.Chunk, error) {
+	c := new(chunk.Chunk)
+	if err := c.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,292 @@
+package series
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dtynn/winston/pkg/chunk"
+	"github.com/dtynn/winston/pkg/storage"
+	"github.com/dtynn/winston/pkg/storage/boltdb"
+	"github.com/dtynn/winston/pkg/storage/goleveldb"
+	"github.com/dtynn/winston/pkg/storage/memory"
+)
+
+func testStorages(t *testing.T, fn func(t *testing.T, s storage.Storage)) {
+	t.Run("boltdb", func(t *testing.T) {
+		s, err := boltdb.Open(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatalf("open boltdb %s", err)
+		}
+
+		defer s.Close()
+		fn(t, s)
+	})
+
+	t.Run("goleveldb", func(t *testing.T) {
+		s, err := goleveldb.Open(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatalf("open goleveldb %s", err)
+		}
+
+		defer s.Close()
+		fn(t, s)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		s, err := memory.Open()
+		if err != nil {
+			t.Fatalf("open memory %s", err)
+		}
+
+		defer s.Close()
+		fn(t, s)
+	})
+}
+
+func TestSeriesBytes(t *testing.T) {
+	s := New("cpu.user", map[string]string{"host": "a", "dc": "x"})
+	if s.Tags[0].Key != "dc" {
+		t.Fatalf("expected sorted tags, got %v", s.Tags)
+	}
+
+	unsorted := Series{
+		Metric: "cpu.user",
+		Tags:   []Tag{{"host", "a"}, {"dc", "x"}},
+	}
+
+	if !reflect.DeepEqual(s.Bytes(), unsorted.Bytes()) {
+		t.Fatalf("expected the same canonical form regardless of tag order")
+	}
+
+	// fields are length prefixed, so the split of metric & tags matters
+	other := New("cpu.userh", map[string]string{"ost": "a", "dc": "x"})
+	if reflect.DeepEqual(s.Bytes(), other.Bytes()) {
+		t.Fatalf("expected different canonical forms")
+	}
+
+	var restored Series
+	if err := restored.UnmarshalBinary(s.Bytes()); err != nil {
+		t.Fatalf("unmarshal %s", err)
+	}
+
+	if !reflect.DeepEqual(restored, s) {
+		t.Fatalf("expected %v, got %v", s, restored)
+	}
+
+	if v, ok := restored.Get("host"); !ok || v != "a" {
+		t.Fatalf("expected tag host=a, got %q", v)
+	}
+
+	if err := restored.UnmarshalBinary([]byte{5, 'a'}); err != ErrMalformedSeries {
+		t.Fatalf("expected ErrMalformedSeries, got %v", err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	testStorages(t, func(t *testing.T, s storage.Storage) {
+		st := NewStore(s, []byte("_series_"))
+
+		cpu := New("cpu.user", map[string]string{"host": "a"})
+		mem := New("mem.used", map[string]string{"host": "a"})
+
+		if _, ok, err := st.Lookup(cpu); err != nil || ok {
+			t.Fatalf("expected unknown series, got %v, %v", ok, err)
+		}
+
+		cpuID, err := st.Assign(cpu)
+		if err != nil {
+			t.Fatalf("assign %s", err)
+		}
+
+		memID, err := st.Assign(mem)
+		if err != nil {
+			t.Fatalf("assign %s", err)
+		}
+
+		if cpuID != 1 || memID != 2 {
+			t.Fatalf("expected ids 1 & 2, got %d & %d", cpuID, memID)
+		}
+
+		if id, err := st.Assign(cpu); err != nil || id != cpuID {
+			t.Fatalf("expected id %d assigned again, got %d, %v", cpuID, id, err)
+		}
+
+		if got, err := st.Series(memID); err != nil || !reflect.DeepEqual(got, mem) {
+			t.Fatalf("expected series %v, got %v, %v", mem, got, err)
+		}
+
+		if _, err := st.Series(100); err != ErrSeriesNotFound {
+			t.Fatalf("expected ErrSeriesNotFound, got %v", err)
+		}
+
+		if _, err := st.Assign(Series{}); err != ErrEmptyMetric {
+			t.Fatalf("expected ErrEmptyMetric, got %v", err)
+		}
+
+		base := time.Unix(1500000000, 0).Truncate(time.Hour)
+		open := chunk.NewChunk(base)
+		if err := st.WriteChunk(cpuID, open); err != ErrChunkNotFinished {
+			t.Fatalf("expected ErrChunkNotFinished, got %v", err)
+		}
+
+		// 4 hourly blocks with points in the first half hour, and a block of another series
+		for b := 0; b < 4; b++ {
+			start := base.Add(time.Duration(b) * time.Hour)
+			ck := chunk.NewChunk(start, chunk.Window(time.Hour))
+			for i := 0; i < 30; i++ {
+				ck.PushFloat64(start.Unix()+int64(i*60), float64(b))
+			}
+
+			ck.Finish()
+			if err := st.WriteChunk(cpuID, ck); err != nil {
+				t.Fatalf("write chunk %s", err)
+			}
+		}
+
+		other := chunk.NewChunk(base)
+		other.PushFloat64(base.Unix(), 1)
+		other.Finish()
+		if err := st.WriteChunk(memID, other); err != nil {
+			t.Fatalf("write chunk %s", err)
+		}
+
+		blocks, err := st.Blocks(cpuID)
+		if err != nil {
+			t.Fatalf("blocks %s", err)
+		}
+
+		if len(blocks) != 4 || !blocks[0].Equal(base) || !blocks[3].Equal(base.Add(3*time.Hour)) {
+			t.Fatalf("unexpected blocks %v", blocks)
+		}
+
+		cases := []struct {
+			since, until time.Time
+			expected     []int64
+		}{
+			{time.Time{}, time.Time{}, []int64{0, 1, 2, 3}},
+			{base.Add(90 * time.Minute), time.Time{}, []int64{2, 3}},
+			{base.Add(70 * time.Minute), base.Add(3 * time.Hour), []int64{1, 2}},
+			{time.Time{}, base.Add(time.Hour + time.Second), []int64{0, 1}},
+			{base.Add(5 * time.Hour), time.Time{}, nil},
+		}
+
+		for i, c := range cases {
+			chunks, err := st.Chunks(cpuID, c.since, c.until)
+			if err != nil {
+				t.Fatalf("#%d chunks %s", i, err)
+			}
+
+			var got []int64
+			for _, ck := range chunks {
+				got = append(got, (ck.Start()-base.Unix())/3600)
+			}
+
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Fatalf("#%d expected blocks %v, got %v", i, c.expected, got)
+			}
+		}
+	})
+}
+
+func TestStoreBlocksBeforeEpoch(t *testing.T) {
+	testStorages(t, func(t *testing.T, s storage.Storage) {
+		st := NewStore(s, []byte("_series_"))
+
+		id, err := st.Assign(New("cpu.user", nil))
+		if err != nil {
+			t.Fatalf("assign %s", err)
+		}
+
+		// hourly blocks around the epoch, written out of order
+		base := time.Unix(0, 0)
+		for _, b := range []int{1, -1, 0, -2} {
+			start := base.Add(time.Duration(b) * time.Hour)
+			ck := chunk.NewChunk(start, chunk.Window(time.Hour))
+			for i := 0; i < 30; i++ {
+				ck.PushFloat64(start.Unix()+int64(i*60), float64(b))
+			}
+
+			ck.Finish()
+			if err := st.WriteChunk(id, ck); err != nil {
+				t.Fatalf("write chunk %s", err)
+			}
+		}
+
+		blocks, err := st.Blocks(id)
+		if err != nil {
+			t.Fatalf("blocks %s", err)
+		}
+
+		var got []int64
+		for _, b := range blocks {
+			got = append(got, b.Unix()/3600)
+		}
+
+		if expected := []int64{-2, -1, 0, 1}; !reflect.DeepEqual(got, expected) {
+			t.Fatalf("expected blocks %v, got %v", expected, got)
+		}
+
+		chunks, err := st.Chunks(id, base.Add(-90*time.Minute), base.Add(30*time.Minute))
+		if err != nil {
+			t.Fatalf("chunks %s", err)
+		}
+
+		got = got[:0]
+		for _, ck := range chunks {
+			got = append(got, ck.Start()/3600)
+		}
+
+		if expected := []int64{-1, 0}; !reflect.DeepEqual(got, expected) {
+			t.Fatalf("expected chunks of blocks %v, got %v", expected, got)
+		}
+	})
+}
+
+func TestAssignConcurrent(t *testing.T) {
+	testStorages(t, func(t *testing.T, s storage.Storage) {
+		// stores sharing a storage assign ids in transactions
+		stores := []*Store{NewStore(s, []byte("_series_")), NewStore(s, []byte("_series_"))}
+		workers, num := 4, 25
+
+		ids := make([][]uint64, workers)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				st := stores[w%len(stores)]
+				for i := 0; i < num; i++ {
+					// every worker assigns the same series
+					id, err := st.Assign(New("cpu", map[string]string{"host": fmt.Sprint(i)}))
+					if err != nil {
+						t.Errorf("assign %s", err)
+						return
+					}
+
+					ids[w] = append(ids[w], id)
+				}
+			}(w)
+		}
+
+		wg.Wait()
+
+		for w := 1; w < workers; w++ {
+			if !reflect.DeepEqual(ids[w], ids[0]) {
+				t.Fatalf("expected the same ids, got %v and %v", ids[0], ids[w])
+			}
+		}
+
+		all, err := stores[0].Select()
+		if err != nil {
+			t.Fatalf("select %s", err)
+		}
+
+		if len(all) != num || all[num-1] != uint64(num) {
+			t.Fatalf("expected ids 1 to %d, got %v", num, all)
+		}
+	})
+}
@@ -5,6 +5,14 @@ import (
 	"github.com/dtynn/winston/pkg/storage"
 )
 
+func newIterator(bucket *bolt.Bucket, start, end []byte) *Iterator {
+	return &Iterator{
+		start: start,
+		end:   end,
+		cur:   bucket.Cursor(),
+	}
+}
+
 // Iterator common iterator
 type Iterator struct {
 	tx  *bolt.Tx
@@ -15,3 +15,13 @@ func Bucket(name []byte) Option {
 		}
 	}
 }
+
+// InitialMmapSize set the initial mmap size of the db.
+// bolt can not grow the mmap while a read-only transaction, e.g. a snapshot or an iterator, is open,
+// so writes are blocked until it is closed. the size should cover the growth of the db while
+// snapshots are held, e.g. for the length of a backup
+func InitialMmapSize(size int) Option {
+	return func(s *Storage) {
+		s.opt.InitialMmapSize = size
+	}
+}
@@ -28,4 +28,17 @@ func TestBoltdbOption(t *testing.T) {
 			return nil
 		})
 	})
+
+	t.Run("OptionInitialMmapSize", func(t *testing.T) {
+		s, err := Open("./testdb/test.db", InitialMmapSize(1<<20))
+		if err != nil {
+			t.Fatalf("open db: %s", err)
+		}
+
+		defer teardownTestStorage(s)
+
+		if s.opt.InitialMmapSize != 1<<20 {
+			t.Errorf("expected initial mmap size %d, got %d", 1<<20, s.opt.InitialMmapSize)
+		}
+	})
 }
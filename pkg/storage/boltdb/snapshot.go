@@ -0,0 +1,69 @@
+package boltdb
+
+import (
+	"github.com/coreos/bbolt"
+	"github.com/dtynn/winston/pkg/storage"
+)
+
+// Snapshot read-only transaction of the bucket, held until the snapshot is released.
+// writes growing the mmap are blocked until then, see InitialMmapSize
+type Snapshot struct {
+	tx     *bolt.Tx
+	bucket *bolt.Bucket
+}
+
+// Snapshot return a point-in-time view of the storage
+func (s *Storage) Snapshot() (storage.Snapshot, error) {
+	tx, err := s.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		tx:     tx,
+		bucket: tx.Bucket(s.bucket),
+	}, nil
+}
+
+// Get return value for specified key, return nil if key not found.
+// the value is valid until the snapshot is released
+func (ss *Snapshot) Get(key []byte) ([]byte, error) {
+	return ss.bucket.Get(key), nil
+}
+
+// MGet return values fro multiple keys
+func (ss *Snapshot) MGet(keys ...[]byte) ([][]byte, error) {
+	vals := make([][]byte, len(keys))
+	for i, key := range keys {
+		vals[i] = ss.bucket.Get(key)
+	}
+
+	return vals, nil
+}
+
+// PrefixIterator return a iterator with prefix
+func (ss *Snapshot) PrefixIterator(prefix []byte) (storage.Iterator, error) {
+	return ss.RangeIterator(prefix, storage.PrefixEnd(prefix))
+}
+
+// RangeIterator return a iterator within the range
+func (ss *Snapshot) RangeIterator(start, end []byte) (storage.Iterator, error) {
+	return &snapshotIterator{
+		Iterator: newIterator(ss.bucket, start, end),
+	}, nil
+}
+
+// Release rollback the read-only transaction
+func (ss *Snapshot) Release() {
+	ss.tx.Rollback()
+}
+
+// snapshotIterator iterator within the tx of a snapshot
+type snapshotIterator struct {
+	*Iterator
+}
+
+// Close close the iter, the tx is kept until the snapshot is released
+func (i *snapshotIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,64 @@
+package boltdb
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dtynn/winston/pkg/storage/test"
+)
+
+func TestBoltdbSnapshot(t *testing.T) {
+	// writes are made while the snapshot is held
+	s := setupTestStorage(t, InitialMmapSize(1<<20))
+	defer teardownTestStorage(s)
+
+	test.Snapshot(t, s)
+}
+
+func TestBoltdbSnapshotGrowMmap(t *testing.T) {
+	// the initial mmap covers the writes made while the snapshot is held
+	s := setupTestStorage(t, InitialMmapSize(256<<20))
+	defer teardownTestStorage(s)
+
+	if err := s.Put([]byte("k"), []byte("v")); err != nil {
+		t.Fatalf("put %s", err)
+	}
+
+	ss, err := s.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot %s", err)
+	}
+
+	defer ss.Release()
+
+	// writes growing the db far beyond the default initial mmap size
+	batch, _ := s.Batch()
+	val := make([]byte, 4<<10)
+	for i := 0; i < 20000; i++ {
+		batch.Put([]byte(fmt.Sprintf("b%08d", i)), val)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- batch.Commit()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("commit %s", err)
+		}
+
+	case <-time.After(30 * time.Second):
+		t.Fatal("writes blocked by the snapshot")
+	}
+
+	if val, err := ss.Get([]byte("k")); err != nil || string(val) != "v" {
+		t.Fatalf("expected v, got %q, %v", val, err)
+	}
+
+	if val, err := ss.Get([]byte("b00000000")); err != nil || val != nil {
+		t.Fatalf("expected nil, got %q, %v", val, err)
+	}
+}
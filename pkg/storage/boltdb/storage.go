@@ -63,7 +63,7 @@ func (s *Storage) Get(key []byte) ([]byte, error) {
 	var val []byte
 
 	if err := s.db.View(func(tx *bolt.Tx) error {
-		val = tx.Bucket(s.bucket).Get(key)
+		val = copyValue(tx.Bucket(s.bucket).Get(key))
 		return nil
 	}); err != nil {
 		return nil, err
@@ -79,11 +79,10 @@ func (s *Storage) MGet(keys ...[]byte) ([][]byte, error) {
 	if err := s.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket(s.bucket)
 		for i, key := range keys {
-			vals[i] = b.Get(key)
+			vals[i] = copyValue(b.Get(key))
 		}
 
 		return nil
-
 	}); err != nil {
 		return nil, err
 	}
@@ -91,6 +90,15 @@ func (s *Storage) MGet(keys ...[]byte) ([][]byte, error) {
 	return vals, nil
 }
 
+// values of bolt are valid only inside the transaction
+func copyValue(val []byte) []byte {
+	if val == nil {
+		return nil
+	}
+
+	return append(make([]byte, 0, len(val)), val...)
+}
+
 // Put udpate the key with val
 func (s *Storage) Put(key, val []byte) error {
 	return s.db.Update(func(tx *bolt.Tx) error {
@@ -121,12 +129,9 @@ func (s *Storage) iterator(start, end []byte) (*Iterator, error) {
 		return nil, err
 	}
 
-	return &Iterator{
-		start: start,
-		end:   end,
-		tx:    tx,
-		cur:   tx.Bucket(s.bucket).Cursor(),
-	}, nil
+	iter := newIterator(tx.Bucket(s.bucket), start, end)
+	iter.tx = tx
+	return iter, nil
 }
 
 // Batch open a batch
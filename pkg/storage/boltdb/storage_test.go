@@ -6,8 +6,8 @@ import (
 	"github.com/dtynn/winston/pkg/storage/test"
 )
 
-func setupTestStorage(t *testing.T) *Storage {
-	s, err := Open("./testdb/test.db")
+func setupTestStorage(t *testing.T, opts ...Option) *Storage {
+	s, err := Open("./testdb/test.db", opts...)
 	if err != nil {
 		t.Fatalf("open storage: %s", err)
 	}
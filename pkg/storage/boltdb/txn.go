@@ -0,0 +1,59 @@
+package boltdb
+
+import (
+	"github.com/coreos/bbolt"
+	"github.com/dtynn/winston/pkg/storage"
+)
+
+// Txn read-write transaction on the bucket
+type Txn struct {
+	bucket *bolt.Bucket
+}
+
+// Get return value for specified key, return nil if key not found
+func (t *Txn) Get(key []byte) ([]byte, error) {
+	return t.bucket.Get(key), nil
+}
+
+// Put update the key with val
+func (t *Txn) Put(key, val []byte) error {
+	return t.bucket.Put(key, val)
+}
+
+// Del delete the key
+func (t *Txn) Del(key []byte) error {
+	return t.bucket.Delete(key)
+}
+
+// Update run fn in a single bolt read-write transaction
+func (s *Storage) Update(fn func(txn storage.Txn) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return fn(&Txn{
+			bucket: tx.Bucket(s.bucket),
+		})
+	})
+}
+
+// CompareAndSwap set the key to new if its value equals old
+func (s *Storage) CompareAndSwap(key, old, new []byte) (bool, error) {
+	var swapped bool
+	err := s.Update(func(txn storage.Txn) error {
+		var err error
+		swapped, err = storage.CompareAndSwap(txn, key, old, new)
+		return err
+	})
+
+	return swapped, err
+}
+
+// Increment add delta to the counter of the key
+func (s *Storage) Increment(key []byte, delta uint64) (uint64, error) {
+	var n uint64
+	err := s.Update(func(txn storage.Txn) error {
+		var err error
+		n, err = storage.Increment(txn, key, delta)
+		return err
+	})
+
+	return n, err
+}
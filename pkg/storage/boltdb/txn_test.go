@@ -0,0 +1,14 @@
+package boltdb
+
+import (
+	"testing"
+
+	"github.com/dtynn/winston/pkg/storage/test"
+)
+
+func TestBoltdbTxn(t *testing.T) {
+	s := setupTestStorage(t)
+	defer teardownTestStorage(s)
+
+	test.Txn(t, s)
+}
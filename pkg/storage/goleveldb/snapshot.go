@@ -0,0 +1,74 @@
+package goleveldb
+
+import (
+	"github.com/dtynn/winston/pkg/storage"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+// Snapshot leveldb snapshot
+type Snapshot struct {
+	ss    *leveldb.Snapshot
+	itopt *opt.ReadOptions
+}
+
+// Snapshot return a point-in-time view of the storage
+func (s *Storage) Snapshot() (storage.Snapshot, error) {
+	return s.snapshot()
+}
+
+func (s *Storage) snapshot() (*Snapshot, error) {
+	ss, err := s.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		ss:    ss,
+		itopt: s.itopt,
+	}, nil
+}
+
+// Get return value for specified key, return nil if key not found
+func (ss *Snapshot) Get(key []byte) ([]byte, error) {
+	val, err := ss.ss.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+
+	return val, err
+}
+
+// MGet return values fro multiple keys
+func (ss *Snapshot) MGet(keys ...[]byte) ([][]byte, error) {
+	vals := make([][]byte, len(keys))
+	for i, k := range keys {
+		val, err := ss.Get(k)
+		if err != nil {
+			return nil, err
+		}
+
+		vals[i] = val
+	}
+
+	return vals, nil
+}
+
+// PrefixIterator return a iterator with prefix
+func (ss *Snapshot) PrefixIterator(prefix []byte) (storage.Iterator, error) {
+	return &Iterator{
+		iter: ss.ss.NewIterator(prefixRange(prefix), ss.itopt),
+	}, nil
+}
+
+// RangeIterator return a iterator within the range
+func (ss *Snapshot) RangeIterator(start, end []byte) (storage.Iterator, error) {
+	return &Iterator{
+		iter: ss.ss.NewIterator(keyRange(start, end), ss.itopt),
+	}, nil
+}
+
+// Release release the snapshot
+func (ss *Snapshot) Release() {
+	ss.ss.Release()
+}
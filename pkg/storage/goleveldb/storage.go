@@ -74,28 +74,14 @@ func (s *Storage) Get(key []byte) ([]byte, error) {
 
 // MGet return values fro multiple keys
 func (s *Storage) MGet(keys ...[]byte) ([][]byte, error) {
-	ss, err := s.db.GetSnapshot()
+	ss, err := s.snapshot()
 	if err != nil {
 		return nil, err
 	}
 
 	defer ss.Release()
 
-	vals := make([][]byte, len(keys))
-	for i, k := range keys {
-		val, err := ss.Get(k, nil)
-		if err == leveldb.ErrNotFound {
-			continue
-		}
-
-		if err != nil {
-			return nil, err
-		}
-
-		vals[i] = val
-	}
-
-	return vals, nil
+	return ss.MGet(keys...)
 }
 
 // Put udpate the key with val
@@ -110,25 +96,12 @@ func (s *Storage) Del(key []byte) error {
 
 // PrefixIterator return a iterator with prefix
 func (s *Storage) PrefixIterator(prefix []byte) (storage.Iterator, error) {
-	var slice *util.Range
-	if prefix != nil {
-		slice = util.BytesPrefix(prefix)
-	}
-
-	return s.iterator(slice)
+	return s.iterator(prefixRange(prefix))
 }
 
 // RangeIterator return a iterator within the range
 func (s *Storage) RangeIterator(start, end []byte) (storage.Iterator, error) {
-	var slice *util.Range
-	if start != nil || end != nil {
-		slice = &util.Range{
-			Start: start,
-			Limit: end,
-		}
-	}
-
-	return s.iterator(slice)
+	return s.iterator(keyRange(start, end))
 }
 
 func (s *Storage) iterator(slice *util.Range) (*Iterator, error) {
@@ -138,6 +111,25 @@ func (s *Storage) iterator(slice *util.Range) (*Iterator, error) {
 	}, nil
 }
 
+func prefixRange(prefix []byte) *util.Range {
+	if prefix == nil {
+		return nil
+	}
+
+	return util.BytesPrefix(prefix)
+}
+
+func keyRange(start, end []byte) *util.Range {
+	if start == nil && end == nil {
+		return nil
+	}
+
+	return &util.Range{
+		Start: start,
+		Limit: end,
+	}
+}
+
 // Batch open a batch
 func (s *Storage) Batch() (storage.Batch, error) {
 	return &Batch{
@@ -0,0 +1,70 @@
+package goleveldb
+
+import (
+	"github.com/dtynn/winston/pkg/storage"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Txn read-write transaction, writes to the db are blocked until it is committed or discarded
+type Txn struct {
+	tx *leveldb.Transaction
+}
+
+// Get return value for specified key, return nil if key not found
+func (t *Txn) Get(key []byte) ([]byte, error) {
+	val, err := t.tx.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+
+	return val, err
+}
+
+// Put update the key with val
+func (t *Txn) Put(key, val []byte) error {
+	return t.tx.Put(key, val, nil)
+}
+
+// Del delete the key
+func (t *Txn) Del(key []byte) error {
+	return t.tx.Delete(key, nil)
+}
+
+// Update run fn in a leveldb transaction
+func (s *Storage) Update(fn func(txn storage.Txn) error) error {
+	tx, err := s.db.OpenTransaction()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(&Txn{tx: tx}); err != nil {
+		tx.Discard()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CompareAndSwap set the key to new if its value equals old
+func (s *Storage) CompareAndSwap(key, old, new []byte) (bool, error) {
+	var swapped bool
+	err := s.Update(func(txn storage.Txn) error {
+		var err error
+		swapped, err = storage.CompareAndSwap(txn, key, old, new)
+		return err
+	})
+
+	return swapped, err
+}
+
+// Increment add delta to the counter of the key
+func (s *Storage) Increment(key []byte, delta uint64) (uint64, error) {
+	var n uint64
+	err := s.Update(func(txn storage.Txn) error {
+		var err error
+		n, err = storage.Increment(txn, key, delta)
+		return err
+	})
+
+	return n, err
+}
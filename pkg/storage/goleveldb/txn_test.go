@@ -0,0 +1,14 @@
+package goleveldb
+
+import (
+	"testing"
+
+	"github.com/dtynn/winston/pkg/storage/test"
+)
+
+func TestGoLeveldbTxn(t *testing.T) {
+	s := setupTestStorage(t)
+	defer teardownTestStorage(s)
+
+	test.Txn(t, s)
+}
@@ -0,0 +1,130 @@
+package key
+
+import (
+	"bytes"
+	"errors"
+)
+
+var (
+	// ErrMalformedEscape escaped bytes without a valid terminator
+	ErrMalformedEscape = errors.New("malformed escaped bytes")
+)
+
+// variable length bytes are escaped & terminated so they keep their order inside composite keys:
+//
+//	0x00 -> 0x00 0xff
+//	end  -> 0x00 0x01
+//
+// a value sorts before any longer value it prefixes, since the terminator is less than any escaped byte.
+const (
+	escapeByte     = 0x00
+	escapedZero    = 0xff
+	escapeTerminal = 0x01
+)
+
+// Blob key formatter wrapper of variable length bytes
+type Blob []byte
+
+// Bytes return bytes
+func (b Blob) Bytes() []byte {
+	return escape(b)
+}
+
+// UnmarshalBinary get bytes from the escaped bytes
+func (b *Blob) UnmarshalBinary(buf []byte) error {
+	data, err := unescape(buf)
+	if err != nil {
+		return err
+	}
+
+	*b = data
+	return nil
+}
+
+// EncodedSize implement VarFormatter
+func (Blob) EncodedSize(buf []byte) (int, error) {
+	return escapedSize(buf)
+}
+
+// Str key formatter wrapper of string
+type Str string
+
+// Bytes return bytes
+func (s Str) Bytes() []byte {
+	return escape([]byte(s))
+}
+
+// UnmarshalBinary get string from the escaped bytes
+func (s *Str) UnmarshalBinary(buf []byte) error {
+	data, err := unescape(buf)
+	if err != nil {
+		return err
+	}
+
+	*s = Str(data)
+	return nil
+}
+
+// EncodedSize implement VarFormatter
+func (Str) EncodedSize(buf []byte) (int, error) {
+	return escapedSize(buf)
+}
+
+func escape(data []byte) []byte {
+	buf := make([]byte, 0, len(data)+2+bytes.Count(data, []byte{escapeByte}))
+	for _, b := range data {
+		buf = append(buf, b)
+		if b == escapeByte {
+			buf = append(buf, escapedZero)
+		}
+	}
+
+	return append(buf, escapeByte, escapeTerminal)
+}
+
+// escapedSize return the size of the escaped value at the start of buf, terminator included
+func escapedSize(buf []byte) (int, error) {
+	for i := 0; i < len(buf); i++ {
+		if buf[i] != escapeByte {
+			continue
+		}
+
+		if i+1 == len(buf) {
+			break
+		}
+
+		switch buf[i+1] {
+		case escapedZero:
+			i++
+
+		case escapeTerminal:
+			return i + 2, nil
+
+		default:
+			return 0, ErrMalformedEscape
+		}
+	}
+
+	return 0, ErrMalformedEscape
+}
+
+func unescape(buf []byte) ([]byte, error) {
+	size, err := escapedSize(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if size != len(buf) {
+		return nil, ErrMalformedKeySize
+	}
+
+	data := make([]byte, 0, size-2)
+	for i := 0; i < size-2; i++ {
+		data = append(data, buf[i])
+		if buf[i] == escapeByte {
+			i++
+		}
+	}
+
+	return data, nil
+}
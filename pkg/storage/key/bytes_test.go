@@ -0,0 +1,91 @@
+package key
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEscaped(t *testing.T) {
+	prefix := []byte("_prefix_")
+
+	values := []string{"", "\x00", "\x00\x00", "\x00\x01", "\x00\xff", "a", "a\x00", "a\x00b", "ab", "b", "\xff"}
+
+	fs := make([]Formatter, len(values))
+	for i, v := range values {
+		fs[i] = Str(v)
+
+		var got Str
+		if err := Unmarshal(Key(prefix, Str(v)), prefix, &got); err != nil {
+			t.Fatalf("unexpected unmarshal error for %q: %s", v, err)
+		}
+
+		if string(got) != v {
+			t.Fatalf("expected %q, got %q", v, got)
+		}
+
+		var blob Blob
+		if err := blob.UnmarshalBinary(Blob(v).Bytes()); err != nil {
+			t.Fatalf("unexpected unmarshal error for %q: %s", v, err)
+		}
+
+		if !bytes.Equal(blob, []byte(v)) {
+			t.Fatalf("expected %q, got %q", v, blob)
+		}
+	}
+
+	checkOrder(t, fs)
+
+	malformed := []struct {
+		buf []byte
+		err error
+	}{
+		{nil, ErrMalformedEscape},
+		{[]byte("a"), ErrMalformedEscape},
+		{[]byte("a\x00"), ErrMalformedEscape},
+		{[]byte("a\x00\x02"), ErrMalformedEscape},
+		{[]byte("a\x00\x01b"), ErrMalformedKeySize},
+	}
+
+	for _, c := range malformed {
+		var got Str
+		if err := got.UnmarshalBinary(c.buf); err != c.err {
+			t.Fatalf("expected %v for %q, got %v", c.err, c.buf, err)
+		}
+	}
+}
+
+func TestFormatters(t *testing.T) {
+	prefix := []byte("_formatters_")
+
+	metric, host, ts, v := Str("cpu\x00user"), Blob("web-1"), SI64(-100), F64(0.5)
+	key := Key(prefix, Formatters{&metric, &host, &ts, &v})
+
+	var rmetric Str
+	var rhost Blob
+	var rts SI64
+	var rv F64
+	if err := Unmarshal(key, prefix, Formatters{&rmetric, &rhost, &rts, &rv}); err != nil {
+		t.Fatal(err)
+	}
+
+	if rmetric != metric || !bytes.Equal(rhost, host) || rts != ts || rv != v {
+		t.Fatalf("unexpected values %q %q %d %v", rmetric, rhost, rts, rv)
+	}
+
+	// prefixes of a composite key are prefixes of the longer ones
+	if !bytes.HasPrefix(key, Key(prefix, Formatters{metric, host})) {
+		t.Fatal("expected the composite key to start with its leading parts")
+	}
+
+	if err := Unmarshal(key[:len(key)-1], prefix, Formatters{&rmetric, &rhost, &rts, &rv}); err != ErrMalformedKeySize {
+		t.Fatalf("expected key size error, got %v", err)
+	}
+
+	if err := Unmarshal(append(key, 0), prefix, Formatters{&rmetric, &rhost, &rts, &rv}); err != ErrMalformedKeySize {
+		t.Fatalf("expected key size error, got %v", err)
+	}
+
+	if err := Unmarshal(key, prefix, Formatters{bytes.NewBuffer(nil)}); err != ErrNotUnmarshaler {
+		t.Fatalf("expected ErrNotUnmarshaler, got %v", err)
+	}
+}
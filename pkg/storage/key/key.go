@@ -12,6 +12,9 @@ import (
 var (
 	// ErrMalformedKeyPrefix key with malformed prefix
 	ErrMalformedKeyPrefix = errors.New("malformed key prefix")
+
+	// ErrNotUnmarshaler the formatter can not be unmarshaled
+	ErrNotUnmarshaler = errors.New("formatter is not an unmarshaler")
 )
 
 // Formatter the content part of a storage key
@@ -27,6 +30,14 @@ type FixedFormatter interface {
 	Size() int
 }
 
+// VarFormatter formatter with variable size, which can be found from the encoded bytes
+type VarFormatter interface {
+	Formatter
+	encoding.BinaryUnmarshaler
+	// EncodedSize return the size of the encoded value at the start of buf
+	EncodedSize(buf []byte) (int, error)
+}
+
 // Key return a storage key of parts
 func Key(prefix []byte, formater Formatter) []byte {
 	f := formater.Bytes()
@@ -66,7 +77,7 @@ func (f FixedFormatters) UnmarshalBinary(buf []byte) error {
 	for i, fm := range f {
 		required := fm.Size()
 		if len(buf) < required {
-			break
+			return ErrMalformedKeySize
 		}
 
 		if err := fm.UnmarshalBinary(buf[:required]); err != nil {
@@ -83,3 +94,57 @@ func (f FixedFormatters) UnmarshalBinary(buf []byte) error {
 
 	return nil
 }
+
+// Formatters a slice of fixed & variable size formatters.
+// each one should be a FixedFormatter or a VarFormatter
+type Formatters []Formatter
+
+// Bytes implement Formater
+func (f Formatters) Bytes() []byte {
+	buf := new(bytes.Buffer)
+	for i := range f {
+		buf.Write(f[i].Bytes())
+	}
+
+	return buf.Bytes()
+}
+
+// UnmarshalBinary implement encoding.BinaryUnmarshaler
+func (f Formatters) UnmarshalBinary(buf []byte) error {
+	for i, fm := range f {
+		var u encoding.BinaryUnmarshaler
+		var size int
+
+		switch v := fm.(type) {
+		case VarFormatter:
+			n, err := v.EncodedSize(buf)
+			if err != nil {
+				return exerr.WithMessage(err, fmt.Sprintf("unmarshaling %T at %d", fm, i))
+			}
+
+			u, size = v, n
+
+		case FixedFormatter:
+			u, size = v, v.Size()
+
+		default:
+			return ErrNotUnmarshaler
+		}
+
+		if len(buf) < size {
+			return ErrMalformedKeySize
+		}
+
+		if err := u.UnmarshalBinary(buf[:size]); err != nil {
+			return exerr.WithMessage(err, fmt.Sprintf("unmarshaling %T at %d", fm, i))
+		}
+
+		buf = buf[size:]
+	}
+
+	if len(buf) != 0 {
+		return ErrMalformedKeySize
+	}
+
+	return nil
+}
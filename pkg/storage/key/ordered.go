@@ -0,0 +1,196 @@
+package key
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var (
+	// ErrMalformedBool a bool is encoded as 0 or 1
+	ErrMalformedBool = errors.New("malformed bool")
+)
+
+// the encodings below keep the order of values in the byte order of keys
+
+// SI64 key formatter wrapper of int64, the sign bit is flipped so negative values sort before positive ones
+type SI64 int64
+
+// Bytes return bytes
+func (i SI64) Bytes() []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(i)^(1<<63))
+	return buf
+}
+
+// UnmarshalBinary get int64 from bytes
+func (i *SI64) UnmarshalBinary(buf []byte) error {
+	if len(buf) != 8 {
+		return ErrMalformedKeySize
+	}
+
+	*i = SI64(binary.BigEndian.Uint64(buf) ^ (1 << 63))
+	return nil
+}
+
+// Size implement FixedFormater
+func (SI64) Size() int {
+	return 8
+}
+
+// SI32 key formatter wrapper of int32, the sign bit is flipped so negative values sort before positive ones
+type SI32 int32
+
+// Bytes return bytes
+func (i SI32) Bytes() []byte {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, uint32(i)^(1<<31))
+	return buf
+}
+
+// UnmarshalBinary get int32 from bytes
+func (i *SI32) UnmarshalBinary(buf []byte) error {
+	if len(buf) != 4 {
+		return ErrMalformedKeySize
+	}
+
+	*i = SI32(binary.BigEndian.Uint32(buf) ^ (1 << 31))
+	return nil
+}
+
+// Size implement FixedFormater
+func (SI32) Size() int {
+	return 4
+}
+
+// F64 key formatter wrapper of float64.
+// positive values have the sign bit flipped, negative ones all bits flipped,
+// so -Inf < negative < -0 < +0 < positive < +Inf, NaNs sort at both ends by their sign
+type F64 float64
+
+// Bytes return bytes
+func (f F64) Bytes() []byte {
+	u := math.Float64bits(float64(f))
+	if u&(1<<63) != 0 {
+		u = ^u
+	} else {
+		u ^= 1 << 63
+	}
+
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, u)
+	return buf
+}
+
+// UnmarshalBinary get float64 from bytes
+func (f *F64) UnmarshalBinary(buf []byte) error {
+	if len(buf) != 8 {
+		return ErrMalformedKeySize
+	}
+
+	u := binary.BigEndian.Uint64(buf)
+	if u&(1<<63) != 0 {
+		u ^= 1 << 63
+	} else {
+		u = ^u
+	}
+
+	*f = F64(math.Float64frombits(u))
+	return nil
+}
+
+// Size implement FixedFormater
+func (F64) Size() int {
+	return 8
+}
+
+// Bool key formatter wrapper of bool, false sorts before true
+type Bool bool
+
+// Bytes return bytes
+func (b Bool) Bytes() []byte {
+	if b {
+		return []byte{1}
+	}
+
+	return []byte{0}
+}
+
+// UnmarshalBinary get bool from bytes
+func (b *Bool) UnmarshalBinary(buf []byte) error {
+	if len(buf) != 1 {
+		return ErrMalformedKeySize
+	}
+
+	switch buf[0] {
+	case 0:
+		*b = false
+
+	case 1:
+		*b = true
+
+	default:
+		return ErrMalformedBool
+	}
+
+	return nil
+}
+
+// Size implement FixedFormater
+func (Bool) Size() int {
+	return 1
+}
+
+// Desc key formatter wrapper inverting the order of F, for newest first scans.
+// all bits of the encoding are flipped, the wrapped formatter should be a pointer to unmarshal into
+type Desc struct {
+	F Formatter
+}
+
+// Bytes return bytes
+func (d Desc) Bytes() []byte {
+	return invert(d.F.Bytes())
+}
+
+// UnmarshalBinary unmarshal the wrapped formatter from the inverted bytes
+func (d Desc) UnmarshalBinary(buf []byte) error {
+	u, ok := d.F.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return ErrNotUnmarshaler
+	}
+
+	return u.UnmarshalBinary(invert(buf))
+}
+
+// Size implement FixedFormater, 0 if the wrapped formatter is not fixed sized
+func (d Desc) Size() int {
+	if f, ok := d.F.(FixedFormatter); ok {
+		return f.Size()
+	}
+
+	return 0
+}
+
+// EncodedSize implement VarFormatter
+func (d Desc) EncodedSize(buf []byte) (int, error) {
+	switch f := d.F.(type) {
+	case VarFormatter:
+		return f.EncodedSize(invert(buf))
+
+	case FixedFormatter:
+		return f.Size(), nil
+
+	default:
+		return 0, ErrNotUnmarshaler
+	}
+}
+
+func invert(buf []byte) []byte {
+	res := make([]byte, len(buf))
+	for i := range buf {
+		res[i] = ^buf[i]
+	}
+
+	return res
+}
@@ -0,0 +1,162 @@
+package key
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// checkOrder check the encodings of the sorted values are sorted
+func checkOrder(t *testing.T, fs []Formatter) {
+	t.Helper()
+
+	keys := make([][]byte, len(fs))
+	for i := range fs {
+		keys[i] = fs[i].Bytes()
+	}
+
+	if !sort.SliceIsSorted(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 }) {
+		t.Fatalf("expected encodings in order of %v", fs)
+	}
+}
+
+func TestOrdered(t *testing.T) {
+	prefix := []byte("_prefix_")
+
+	t.Run("SI64", func(t *testing.T) {
+		ns := []int64{math.MinInt64, -1 << 40, -2, -1, 0, 1, 2, 1 << 40, math.MaxInt64}
+		for i := 0; i < 20; i++ {
+			ns = append(ns, rand.Int63()-rand.Int63())
+		}
+
+		sort.Slice(ns, func(i, j int) bool { return ns[i] < ns[j] })
+
+		fs := make([]Formatter, len(ns))
+		for i, n := range ns {
+			fs[i] = SI64(n)
+
+			var got SI64
+			if err := Unmarshal(Key(prefix, SI64(n)), prefix, &got); err != nil {
+				t.Fatalf("unexpected unmarshal error for %d: %s", n, err)
+			}
+
+			if int64(got) != n {
+				t.Fatalf("expected %d, got %d", n, got)
+			}
+		}
+
+		checkOrder(t, fs)
+
+		var got SI64
+		if err := got.UnmarshalBinary(make([]byte, 7)); err != ErrMalformedKeySize {
+			t.Fatalf("expected key size error, got %v", err)
+		}
+	})
+
+	t.Run("SI32", func(t *testing.T) {
+		ns := []int32{math.MinInt32, -1 << 20, -1, 0, 1, 1 << 20, math.MaxInt32}
+		fs := make([]Formatter, len(ns))
+		for i, n := range ns {
+			fs[i] = SI32(n)
+
+			var got SI32
+			if err := Unmarshal(Key(prefix, SI32(n)), prefix, &got); err != nil {
+				t.Fatalf("unexpected unmarshal error for %d: %s", n, err)
+			}
+
+			if int32(got) != n {
+				t.Fatalf("expected %d, got %d", n, got)
+			}
+		}
+
+		checkOrder(t, fs)
+	})
+
+	t.Run("F64", func(t *testing.T) {
+		ns := []float64{
+			math.Inf(-1), -math.MaxFloat64, -1e10, -1, -math.SmallestNonzeroFloat64,
+			math.Copysign(0, -1), 0, math.SmallestNonzeroFloat64, 0.5, 1, 1e10, math.MaxFloat64, math.Inf(1),
+		}
+
+		fs := make([]Formatter, len(ns))
+		for i, n := range ns {
+			fs[i] = F64(n)
+
+			var got F64
+			if err := Unmarshal(Key(prefix, F64(n)), prefix, &got); err != nil {
+				t.Fatalf("unexpected unmarshal error for %v: %s", n, err)
+			}
+
+			if math.Float64bits(float64(got)) != math.Float64bits(n) {
+				t.Fatalf("expected %v, got %v", n, got)
+			}
+		}
+
+		checkOrder(t, fs)
+
+		var got F64
+		if err := got.UnmarshalBinary(F64(math.NaN()).Bytes()); err != nil || !math.IsNaN(float64(got)) {
+			t.Fatalf("expected NaN, got %v, %v", got, err)
+		}
+	})
+
+	t.Run("Bool", func(t *testing.T) {
+		checkOrder(t, []Formatter{Bool(false), Bool(true)})
+
+		for _, b := range []bool{false, true} {
+			var got Bool
+			if err := got.UnmarshalBinary(Bool(b).Bytes()); err != nil || bool(got) != b {
+				t.Fatalf("expected %v, got %v, %v", b, got, err)
+			}
+		}
+
+		var got Bool
+		if err := got.UnmarshalBinary([]byte{2}); err != ErrMalformedBool {
+			t.Fatalf("expected ErrMalformedBool, got %v", err)
+		}
+	})
+
+	t.Run("Desc", func(t *testing.T) {
+		checkOrder(t, []Formatter{
+			Desc{SI64(1 << 40)},
+			Desc{SI64(1)},
+			Desc{SI64(-1)},
+		})
+
+		checkOrder(t, []Formatter{
+			Desc{Str("b")},
+			Desc{Str("ab")},
+			Desc{Str("a\x00")},
+			Desc{Str("a")},
+			Desc{Str("")},
+		})
+
+		n := SI64(-42)
+		var got SI64
+		if err := Unmarshal(Key(prefix, Desc{n}), prefix, Desc{&got}); err != nil {
+			t.Fatalf("unexpected unmarshal error: %s", err)
+		}
+
+		if got != n {
+			t.Fatalf("expected %d, got %d", n, got)
+		}
+
+		// newest first keys of a series
+		id, ts, s := UI64(7), SI64(-1), Str("a\x00b")
+		key := Key(prefix, Formatters{&id, Desc{&ts}, Desc{&s}, Bool(true)})
+
+		var rid UI64
+		var rts SI64
+		var rs Str
+		var rb Bool
+		if err := Unmarshal(key, prefix, Formatters{&rid, Desc{&rts}, Desc{&rs}, &rb}); err != nil {
+			t.Fatalf("unexpected unmarshal error: %s", err)
+		}
+
+		if rid != id || rts != ts || rs != s || !rb {
+			t.Fatalf("unexpected values %d %d %q %v", rid, rts, rs, rb)
+		}
+	})
+}
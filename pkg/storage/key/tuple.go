@@ -0,0 +1,306 @@
+package key
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	exerr "github.com/pkg/errors"
+)
+
+var (
+	// ErrUnsupportedType the value can not be an element of a tuple
+	ErrUnsupportedType = errors.New("unsupported tuple element type")
+
+	// ErrMalformedTuple unknown type tag or truncated element
+	ErrMalformedTuple = errors.New("malformed tuple")
+
+	// ErrTupleLength the tuple does not match the fields of the struct
+	ErrTupleLength = errors.New("tuple length mismatch")
+)
+
+// tuple layout:
+//
+//	(type tag(1) | element)*
+//
+// elements are encoded by the order preserving formatters, variable length ones escaped & terminated.
+// elements of different types sort by the type tag, ints & uints are different types.
+const (
+	tupleNil byte = iota + 1
+	tupleBytes
+	tupleString
+	tupleInt
+	tupleUint
+	tupleFloat
+	tupleBool
+	tupleTime
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Tuple elements of a composite key.
+// supported elements are nil, []byte, string, ints, uints, floats, bool & time.Time,
+// they are decoded as nil, []byte, string, int64, uint64, float64, bool & time.Time.
+// the key of a leading subset of the elements is a prefix of the full key, which can be used for PrefixIterator
+type Tuple []interface{}
+
+// Key return the storage key of the elements
+func (t Tuple) Key(prefix []byte) ([]byte, error) {
+	key := append([]byte(nil), prefix...)
+	for i, elem := range t {
+		var err error
+		if key, err = appendElement(key, elem); err != nil {
+			return nil, exerr.WithMessage(err, fmt.Sprintf("encoding %T at %d", elem, i))
+		}
+	}
+
+	return key, nil
+}
+
+func appendElement(buf []byte, elem interface{}) ([]byte, error) {
+	if elem == nil {
+		return append(buf, tupleNil), nil
+	}
+
+	if t, ok := elem.(time.Time); ok {
+		return append(append(buf, tupleTime), SI64(t.UnixNano()).Bytes()...), nil
+	}
+
+	v := reflect.ValueOf(elem)
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return nil, ErrUnsupportedType
+		}
+
+		return append(append(buf, tupleBytes), escape(v.Bytes())...), nil
+
+	case reflect.String:
+		return append(append(buf, tupleString), escape([]byte(v.String()))...), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return append(append(buf, tupleInt), SI64(v.Int()).Bytes()...), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return append(append(buf, tupleUint), UI64(v.Uint()).Bytes()...), nil
+
+	case reflect.Float32, reflect.Float64:
+		return append(append(buf, tupleFloat), F64(v.Float()).Bytes()...), nil
+
+	case reflect.Bool:
+		return append(append(buf, tupleBool), Bool(v.Bool()).Bytes()...), nil
+
+	default:
+		return nil, ErrUnsupportedType
+	}
+}
+
+// UnmarshalBinary implement encoding.BinaryUnmarshaler
+func (t *Tuple) UnmarshalBinary(buf []byte) error {
+	var res Tuple
+	for len(buf) > 0 {
+		elem, n, err := readElement(buf)
+		if err != nil {
+			return exerr.WithMessage(err, fmt.Sprintf("decoding element at %d", len(res)))
+		}
+
+		res = append(res, elem)
+		buf = buf[n:]
+	}
+
+	*t = res
+	return nil
+}
+
+// readElement return the element at the start of buf & the size it takes
+func readElement(buf []byte) (interface{}, int, error) {
+	tag, data := buf[0], buf[1:]
+
+	var fixed FixedFormatter
+	switch tag {
+	case tupleNil:
+		return nil, 1, nil
+
+	case tupleBytes, tupleString:
+		size, err := escapedSize(data)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		b, err := unescape(data[:size])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if tag == tupleString {
+			return string(b), 1 + size, nil
+		}
+
+		return b, 1 + size, nil
+
+	case tupleInt, tupleTime:
+		fixed = new(SI64)
+
+	case tupleUint:
+		fixed = new(UI64)
+
+	case tupleFloat:
+		fixed = new(F64)
+
+	case tupleBool:
+		fixed = new(Bool)
+
+	default:
+		return nil, 0, ErrMalformedTuple
+	}
+
+	size := fixed.Size()
+	if len(data) < size {
+		return nil, 0, ErrMalformedTuple
+	}
+
+	if err := fixed.UnmarshalBinary(data[:size]); err != nil {
+		return nil, 0, err
+	}
+
+	var elem interface{}
+	switch f := fixed.(type) {
+	case *SI64:
+		if tag == tupleTime {
+			elem = time.Unix(0, int64(*f))
+		} else {
+			elem = int64(*f)
+		}
+
+	case *UI64:
+		elem = uint64(*f)
+
+	case *F64:
+		elem = float64(*f)
+
+	case *Bool:
+		elem = bool(*f)
+	}
+
+	return elem, 1 + size, nil
+}
+
+// StructTuple return the exported fields of the struct in order as a tuple,
+// fields tagged with `key:"-"` are skipped
+func StructTuple(v interface{}) (Tuple, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, ErrUnsupportedType
+	}
+
+	var t Tuple
+	for _, idx := range tupleFields(rv.Type()) {
+		t = append(t, rv.Field(idx).Interface())
+	}
+
+	return t, nil
+}
+
+// Decode set the elements to the fields of the struct v points to, in the order of StructTuple
+func (t Tuple) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return ErrUnsupportedType
+	}
+
+	rv = rv.Elem()
+	fields := tupleFields(rv.Type())
+	if len(fields) != len(t) {
+		return ErrTupleLength
+	}
+
+	for i, idx := range fields {
+		if err := setField(rv.Field(idx), t[i]); err != nil {
+			return exerr.WithMessage(err, fmt.Sprintf("decoding field %s", rv.Type().Field(idx).Name))
+		}
+	}
+
+	return nil
+}
+
+func tupleFields(typ reflect.Type) []int {
+	var fields []int
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("key") == "-" {
+			continue
+		}
+
+		fields = append(fields, i)
+	}
+
+	return fields
+}
+
+func setField(f reflect.Value, elem interface{}) error {
+	if elem == nil {
+		f.Set(reflect.Zero(f.Type()))
+		return nil
+	}
+
+	if f.Type() == timeType {
+		t, ok := elem.(time.Time)
+		if !ok {
+			return ErrUnsupportedType
+		}
+
+		f.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch e := elem.(type) {
+	case []byte:
+		if f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Uint8 {
+			f.SetBytes(e)
+			return nil
+		}
+
+	case string:
+		if f.Kind() == reflect.String {
+			f.SetString(e)
+			return nil
+		}
+
+	case int64:
+		switch f.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if f.OverflowInt(e) {
+				return ErrUnsupportedType
+			}
+
+			f.SetInt(e)
+			return nil
+		}
+
+	case uint64:
+		switch f.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if f.OverflowUint(e) {
+				return ErrUnsupportedType
+			}
+
+			f.SetUint(e)
+			return nil
+		}
+
+	case float64:
+		if f.Kind() == reflect.Float32 || f.Kind() == reflect.Float64 {
+			f.SetFloat(e)
+			return nil
+		}
+
+	case bool:
+		if f.Kind() == reflect.Bool {
+			f.SetBool(e)
+			return nil
+		}
+	}
+
+	return ErrUnsupportedType
+}
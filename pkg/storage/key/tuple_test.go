@@ -0,0 +1,170 @@
+package key
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	exerr "github.com/pkg/errors"
+)
+
+func TestTuple(t *testing.T) {
+	prefix := []byte("_tuple_")
+	ts := time.Unix(0, 1234567890)
+
+	tuple := Tuple{nil, []byte("a\x00b"), "cpu", int64(-3), uint64(math.MaxUint64), 0.25, true, ts}
+	key, err := tuple.Key(prefix)
+	if err != nil {
+		t.Fatalf("encode %s", err)
+	}
+
+	var got Tuple
+	if err := Unmarshal(key, prefix, &got); err != nil {
+		t.Fatalf("decode %s", err)
+	}
+
+	if !reflect.DeepEqual(got, tuple) {
+		t.Fatalf("expected %v, got %v", tuple, got)
+	}
+
+	// other int & float types are widened
+	key, err = Tuple{int8(-1), uint16(2), float32(0.5), Str("s")}.Key(nil)
+	if err != nil {
+		t.Fatalf("encode %s", err)
+	}
+
+	if err := got.UnmarshalBinary(key); err != nil {
+		t.Fatalf("decode %s", err)
+	}
+
+	if expected := (Tuple{int64(-1), uint64(2), float64(0.5), "s"}); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	if _, err := (Tuple{struct{}{}}).Key(nil); exerr.Cause(err) != ErrUnsupportedType {
+		t.Fatalf("expected ErrUnsupportedType, got %v", err)
+	}
+
+	if _, err := (Tuple{[]int{1}}).Key(nil); exerr.Cause(err) != ErrUnsupportedType {
+		t.Fatalf("expected ErrUnsupportedType, got %v", err)
+	}
+
+	for _, buf := range [][]byte{{0}, {tupleInt, 0}, {tupleString, 'a'}, {tupleBool, 2}} {
+		if err := got.UnmarshalBinary(buf); err == nil {
+			t.Fatalf("expected error for %v", buf)
+		}
+	}
+}
+
+func TestTupleOrder(t *testing.T) {
+	tuples := []Tuple{
+		{"cpu", "a", int64(-10)},
+		{"cpu", "a", int64(-1)},
+		{"cpu", "a", int64(0)},
+		{"cpu", "a", int64(5)},
+		{"cpu", "a\x00", int64(-5)},
+		{"cpu", "ab", int64(-5)},
+		{"cpu", "b"},
+		{"cpu", "b", int64(-5)},
+		{"cpu.user", "a"},
+		{"mem", 1.5},
+	}
+
+	keys := make([][]byte, len(tuples))
+	for i, tuple := range tuples {
+		key, err := tuple.Key(nil)
+		if err != nil {
+			t.Fatalf("encode %s", err)
+		}
+
+		keys[i] = key
+	}
+
+	for i := 1; i < len(keys); i++ {
+		if bytes.Compare(keys[i-1], keys[i]) >= 0 {
+			t.Fatalf("expected %v before %v", tuples[i-1], tuples[i])
+		}
+	}
+}
+
+type testTupleStruct struct {
+	Metric string
+	Host   []byte
+	Shard  uint8
+	Start  time.Time
+	Value  float32
+	Skip   int `key:"-"`
+	hidden int
+}
+
+func TestTupleStruct(t *testing.T) {
+	prefix := []byte("_struct_")
+	v := testTupleStruct{
+		Metric: "cpu",
+		Host:   []byte("web-1"),
+		Shard:  3,
+		Start:  time.Unix(100, 0),
+		Value:  1.5,
+		Skip:   7,
+		hidden: 8,
+	}
+
+	tuple, err := StructTuple(&v)
+	if err != nil {
+		t.Fatalf("struct tuple %s", err)
+	}
+
+	if len(tuple) != 5 {
+		t.Fatalf("expected 5 elements, got %v", tuple)
+	}
+
+	key, err := tuple.Key(prefix)
+	if err != nil {
+		t.Fatalf("encode %s", err)
+	}
+
+	// the key of the leading fields is a prefix of the full key
+	leading, err := tuple[:2].Key(prefix)
+	if err != nil {
+		t.Fatalf("encode %s", err)
+	}
+
+	if !bytes.HasPrefix(key, leading) {
+		t.Fatal("expected the key of leading fields to be a prefix")
+	}
+
+	other, _ := Tuple{"cpu", []byte("web-10")}.Key(prefix)
+	if bytes.HasPrefix(other, leading) {
+		t.Fatal("expected the prefix to end at the element boundary")
+	}
+
+	var decoded Tuple
+	if err := Unmarshal(key, prefix, &decoded); err != nil {
+		t.Fatalf("decode %s", err)
+	}
+
+	var got testTupleStruct
+	if err := decoded.Decode(&got); err != nil {
+		t.Fatalf("decode struct %s", err)
+	}
+
+	v.Skip, v.hidden = 0, 0
+	if !reflect.DeepEqual(got, v) {
+		t.Fatalf("expected %+v, got %+v", v, got)
+	}
+
+	if err := decoded[:2].Decode(&got); err != ErrTupleLength {
+		t.Fatalf("expected ErrTupleLength, got %v", err)
+	}
+
+	if err := decoded.Decode(got); err != ErrUnsupportedType {
+		t.Fatalf("expected ErrUnsupportedType for a non pointer, got %v", err)
+	}
+
+	wrong := Tuple{"cpu", []byte("web-1"), uint64(256), time.Unix(100, 0), 1.5}
+	if err := wrong.Decode(&got); exerr.Cause(err) != ErrUnsupportedType {
+		t.Fatalf("expected ErrUnsupportedType for an overflowing field, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/dtynn/winston/pkg/storage"
+)
+
+type batchOp struct {
+	key []byte
+	val []byte
+	del bool
+}
+
+// Batch batch operation, applied in order as a single write
+type Batch struct {
+	s      *Storage
+	ops    []batchOp
+	closed bool
+	sync.Mutex
+}
+
+// Put update a key
+func (b *Batch) Put(key, val []byte) error {
+	b.ops = append(b.ops, batchOp{
+		key: copyBytes(key),
+		val: copyValue(val),
+	})
+
+	return nil
+}
+
+// Del delete a key
+func (b *Batch) Del(key []byte) error {
+	b.ops = append(b.ops, batchOp{
+		key: copyBytes(key),
+		del: true,
+	})
+
+	return nil
+}
+
+// Commit commit the changes
+func (b *Batch) Commit() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.closed {
+		return storage.ErrBatchClosed
+	}
+
+	if err := b.s.write(func(t *tree) {
+		for _, op := range b.ops {
+			if op.del {
+				t.del(op.key)
+				continue
+			}
+
+			t.put(op.key, op.val)
+		}
+	}); err != nil {
+		return err
+	}
+
+	b.closed = true
+	b.ops = nil
+
+	return nil
+}
+
+// Close close the batch
+func (b *Batch) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.closed {
+		return storage.ErrBatchClosed
+	}
+
+	b.closed = true
+	b.ops = nil
+
+	return nil
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/dtynn/winston/pkg/storage/test"
+)
+
+func TestMemoryBatch(t *testing.T) {
+	s := setupTestStorage(t)
+	defer teardownTestStorage(s)
+
+	test.Batch(t, s)
+}
@@ -0,0 +1,243 @@
+package memory
+
+import (
+	"github.com/dtynn/winston/pkg/storage"
+)
+
+func newIterator(root *node, start, end []byte) *Iterator {
+	return &Iterator{
+		root:  root,
+		start: start,
+		end:   end,
+	}
+}
+
+// frame a node on the path & an index in it.
+// the index is the current item for the last frame, the child the path goes through for the others
+type frame struct {
+	n *node
+	i int
+}
+
+// Iterator iterator over a frozen root, writes after it is created are not visible
+type Iterator struct {
+	root *node
+
+	// path from the root to the current item
+	path []frame
+
+	start, end []byte
+
+	moved bool
+}
+
+// First move to the first entry
+func (i *Iterator) First() {
+	i.moved = true
+
+	if i.start == nil {
+		i.path = i.path[:0]
+		i.leftmost(i.root)
+		i.check()
+		return
+	}
+
+	i.Seek(i.start)
+}
+
+// Last move to the last entry
+func (i *Iterator) Last() {
+	i.moved = true
+	i.path = i.path[:0]
+
+	if i.end == nil {
+		i.rightmost(i.root)
+	} else {
+		i.seekBefore(i.end)
+	}
+
+	i.check()
+}
+
+// Seek move to the key equal or greater than seek
+func (i *Iterator) Seek(seek []byte) {
+	i.moved = true
+
+	if !storage.KeyInRange(seek, i.start, nil) {
+		seek = i.start
+	}
+
+	i.path = i.path[:0]
+	for n := i.root; n != nil; {
+		idx, found := n.search(seek)
+		i.path = append(i.path, frame{n, idx})
+		if found {
+			break
+		}
+
+		if n.leaf() {
+			if idx == len(n.items) {
+				i.up()
+			}
+
+			break
+		}
+
+		n = n.children[idx]
+	}
+
+	i.check()
+}
+
+// seekBefore move to the greatest item < key
+func (i *Iterator) seekBefore(key []byte) {
+	for n := i.root; n != nil; {
+		idx, found := n.search(key)
+		if n.leaf() {
+			i.path = append(i.path, frame{n, idx - 1})
+			if idx == 0 {
+				i.upBefore()
+			}
+
+			return
+		}
+
+		i.path = append(i.path, frame{n, idx})
+		if found {
+			i.rightmost(n.children[idx])
+			return
+		}
+
+		n = n.children[idx]
+	}
+}
+
+// Next move to the next key
+func (i *Iterator) Next() bool {
+	if !i.moved {
+		i.First()
+		return i.Valid()
+	}
+
+	if len(i.path) == 0 {
+		return false
+	}
+
+	cur := &i.path[len(i.path)-1]
+	cur.i++
+	if !cur.n.leaf() {
+		i.leftmost(cur.n.children[cur.i])
+	} else if cur.i == len(cur.n.items) {
+		i.up()
+	}
+
+	i.check()
+	return i.Valid()
+}
+
+// Prev move to the previous key
+func (i *Iterator) Prev() bool {
+	if !i.moved {
+		i.Last()
+		return i.Valid()
+	}
+
+	if len(i.path) == 0 {
+		return false
+	}
+
+	cur := &i.path[len(i.path)-1]
+	if !cur.n.leaf() {
+		i.rightmost(cur.n.children[cur.i])
+	} else if cur.i--; cur.i < 0 {
+		i.upBefore()
+	}
+
+	i.check()
+	return i.Valid()
+}
+
+// up pop the last frame, then up to the first ancestor with an item after the child the path goes through
+func (i *Iterator) up() {
+	for i.path = i.path[:len(i.path)-1]; len(i.path) > 0; i.path = i.path[:len(i.path)-1] {
+		if f := i.path[len(i.path)-1]; f.i < len(f.n.items) {
+			return
+		}
+	}
+}
+
+// upBefore pop the last frame, then up to the first ancestor with an item before the child the path goes through
+func (i *Iterator) upBefore() {
+	for i.path = i.path[:len(i.path)-1]; len(i.path) > 0; i.path = i.path[:len(i.path)-1] {
+		if f := &i.path[len(i.path)-1]; f.i > 0 {
+			f.i--
+			return
+		}
+	}
+}
+
+func (i *Iterator) leftmost(n *node) {
+	for ; n != nil; n = n.children[0] {
+		i.path = append(i.path, frame{n, 0})
+		if n.leaf() {
+			return
+		}
+	}
+}
+
+func (i *Iterator) rightmost(n *node) {
+	for ; n != nil; n = n.children[len(n.items)] {
+		if n.leaf() {
+			i.path = append(i.path, frame{n, len(n.items) - 1})
+			return
+		}
+
+		i.path = append(i.path, frame{n, len(n.items)})
+	}
+}
+
+func (i *Iterator) current() item {
+	f := i.path[len(i.path)-1]
+	return f.n.items[f.i]
+}
+
+// check invalidate the iterator if the current item is out of the range
+func (i *Iterator) check() {
+	if len(i.path) > 0 && !storage.KeyInRange(i.current().key, i.start, i.end) {
+		i.path = i.path[:0]
+	}
+}
+
+// Key current key of the cursor, keys & values of a frozen root are never modified so they are not copied
+func (i *Iterator) Key() []byte {
+	if !i.Valid() {
+		return nil
+	}
+
+	return i.current().key
+}
+
+// Value current value of the cursor
+func (i *Iterator) Value() []byte {
+	if !i.Valid() {
+		return nil
+	}
+
+	return i.current().val
+}
+
+// Valid if the current entry is valid
+func (i *Iterator) Valid() bool {
+	return len(i.path) > 0
+}
+
+// Close close the iter
+func (i *Iterator) Close() error {
+	i.root, i.path = nil, nil
+	return nil
+}
+
+// Err return error if any during cursor moves
+func (i *Iterator) Err() error {
+	return nil
+}
@@ -0,0 +1,16 @@
+package memory
+
+const (
+	defaultDegree = 16
+)
+
+// Option storage option
+type Option func(s *Storage)
+
+// Degree set the degree of the b-tree, a node holds at most 2*degree-1 keys.
+// a larger degree makes the tree shallower, but writes after a snapshot copy larger nodes
+func Degree(degree int) Option {
+	return func(s *Storage) {
+		s.degree = degree
+	}
+}
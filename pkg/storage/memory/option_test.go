@@ -0,0 +1,20 @@
+package memory
+
+import (
+	"testing"
+)
+
+func TestMemoryOption(t *testing.T) {
+	t.Run("OptionDegree", func(t *testing.T) {
+		s := setupTestStorage(t, Degree(4))
+		defer teardownTestStorage(s)
+
+		if s.t.max != 7 {
+			t.Errorf("expected max node size %d, got %d", 7, s.t.max)
+		}
+
+		if _, err := Open(Degree(1)); err != ErrInvalidDegree {
+			t.Errorf("expected ErrInvalidDegree, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"github.com/dtynn/winston/pkg/storage"
+)
+
+// Snapshot the frozen root of the storage when it is taken
+type Snapshot struct {
+	t tree
+}
+
+// Snapshot return a point-in-time view of the storage,
+// it costs the nodes it keeps alive & the copies made by the first writes to them
+func (s *Storage) Snapshot() (storage.Snapshot, error) {
+	root, err := s.freeze()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		t: tree{
+			root: root,
+		},
+	}, nil
+}
+
+// Get return value for specified key, return nil if key not found
+func (ss *Snapshot) Get(key []byte) ([]byte, error) {
+	return lookup(&ss.t, key), nil
+}
+
+// MGet return values fro multiple keys
+func (ss *Snapshot) MGet(keys ...[]byte) ([][]byte, error) {
+	return mlookup(&ss.t, keys), nil
+}
+
+// PrefixIterator return a iterator with prefix
+func (ss *Snapshot) PrefixIterator(prefix []byte) (storage.Iterator, error) {
+	return newIterator(ss.t.root, prefix, storage.PrefixEnd(prefix)), nil
+}
+
+// RangeIterator return a iterator within the range
+func (ss *Snapshot) RangeIterator(start, end []byte) (storage.Iterator, error) {
+	return newIterator(ss.t.root, start, end), nil
+}
+
+// Release release the snapshot
+func (ss *Snapshot) Release() {
+	ss.t.root = nil
+}
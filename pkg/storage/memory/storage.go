@@ -0,0 +1,193 @@
+// Package memory implements storage.Storage in memory, on top of a copy-on-write b-tree.
+package memory
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/dtynn/winston/pkg/storage"
+)
+
+var (
+	// ErrClosed the storage is closed
+	ErrClosed = errors.New("memory storage closed")
+
+	// ErrInvalidDegree the degree of the b-tree is less than 2
+	ErrInvalidDegree = errors.New("invalid degree")
+)
+
+// Open return a memory storage
+func Open(opts ...Option) (*Storage, error) {
+	s := &Storage{
+		degree: defaultDegree,
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	if s.degree < 2 {
+		return nil, ErrInvalidDegree
+	}
+
+	s.t.max = 2*s.degree - 1
+	return s, nil
+}
+
+// Storage storage implementation.
+// writes are serialized & modify the nodes of the tree in place, until a snapshot or an iterator freezes them.
+// a transaction writes copies of the nodes, published on commit, so reads are not blocked while it runs
+type Storage struct {
+	degree int
+
+	// mu serialize writes, rw guard the tree against in place writes
+	mu     sync.Mutex
+	rw     sync.RWMutex
+	t      tree
+	closed bool
+}
+
+// freeze return the current root, later writes copy its nodes before modifying them
+func (s *Storage) freeze() (*node, error) {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+
+	s.t.cow = nil
+	return s.t.root, nil
+}
+
+// write run fn on the tree in place
+func (s *Storage) write(fn func(t *tree)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	if s.t.cow == nil {
+		s.t.cow = new(cow)
+	}
+
+	fn(&s.t)
+	return nil
+}
+
+// Get return value for specified key, return nil if key not found
+func (s *Storage) Get(key []byte) ([]byte, error) {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+
+	return lookup(&s.t, key), nil
+}
+
+// MGet return values fro multiple keys
+func (s *Storage) MGet(keys ...[]byte) ([][]byte, error) {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+
+	return mlookup(&s.t, keys), nil
+}
+
+// Put udpate the key with val
+func (s *Storage) Put(key, val []byte) error {
+	key, val = copyBytes(key), copyValue(val)
+	return s.write(func(t *tree) {
+		t.put(key, val)
+	})
+}
+
+// Del delete the key
+func (s *Storage) Del(key []byte) error {
+	return s.write(func(t *tree) {
+		t.del(key)
+	})
+}
+
+// PrefixIterator return a iterator with prefix
+func (s *Storage) PrefixIterator(prefix []byte) (storage.Iterator, error) {
+	return s.RangeIterator(prefix, storage.PrefixEnd(prefix))
+}
+
+// RangeIterator return a iterator within the range
+func (s *Storage) RangeIterator(start, end []byte) (storage.Iterator, error) {
+	root, err := s.freeze()
+	if err != nil {
+		return nil, err
+	}
+
+	return newIterator(root, start, end), nil
+}
+
+// Batch open a batch
+func (s *Storage) Batch() (storage.Batch, error) {
+	return &Batch{
+		s: s,
+	}, nil
+}
+
+// Close close the storage & drop the data
+func (s *Storage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	s.closed = true
+	s.t.root, s.t.cow = nil, nil
+	return nil
+}
+
+// GC garbage collection
+func (s *Storage) GC() error {
+	return nil
+}
+
+// lookup return a copy of the value, values returned by Get are owned by the caller like the other storages
+func lookup(t *tree, key []byte) []byte {
+	val, _ := t.get(key)
+	return copyBytes(val)
+}
+
+func mlookup(t *tree, keys [][]byte) [][]byte {
+	vals := make([][]byte, len(keys))
+	for i, key := range keys {
+		vals[i] = lookup(t, key)
+	}
+
+	return vals
+}
+
+// copyBytes keys & values are copied in, so the stored ones are never modified by callers
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	return append(make([]byte, 0, len(b)), b...)
+}
+
+// copyValue a nil val is stored as empty
+func copyValue(val []byte) []byte {
+	return append(make([]byte, 0, len(val)), val...)
+}
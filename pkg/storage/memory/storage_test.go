@@ -0,0 +1,180 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/dtynn/winston/pkg/storage/test"
+)
+
+func setupTestStorage(t *testing.T, opts ...Option) *Storage {
+	s, err := Open(opts...)
+	if err != nil {
+		t.Fatalf("open storage: %s", err)
+	}
+
+	return s
+}
+
+func teardownTestStorage(s *Storage) {
+	s.Close()
+}
+
+func TestMemoryUpdate(t *testing.T) {
+	s := setupTestStorage(t)
+	defer teardownTestStorage(s)
+
+	test.StorageUpdate(t, s)
+}
+
+func TestMemoryClose(t *testing.T) {
+	s := setupTestStorage(t)
+	if err := s.Put([]byte("a"), []byte("1")); err != nil {
+		t.Fatalf("put %s", err)
+	}
+
+	ss, err := s.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot %s", err)
+	}
+
+	defer ss.Release()
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("close %s", err)
+	}
+
+	if _, err := s.Get([]byte("a")); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+
+	if err := s.Put([]byte("a"), []byte("2")); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+
+	if err := s.Close(); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+
+	// snapshots taken before keep their view
+	if val, err := ss.Get([]byte("a")); err != nil || string(val) != "1" {
+		t.Fatalf("expected 1, got %q, %v", val, err)
+	}
+}
+
+func TestMemoryConcurrent(t *testing.T) {
+	s := setupTestStorage(t, Degree(2))
+	defer teardownTestStorage(s)
+
+	num := 2000
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < num; i++ {
+			s.Put(benchmarkKey(i), []byte{byte(i)})
+			if i%3 == 0 {
+				s.Del(benchmarkKey(i / 2))
+			}
+		}
+	}()
+
+	// readers see a consistent view while the writer modifies the tree in place
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+
+		iter, err := s.PrefixIterator(nil)
+		if err != nil {
+			t.Fatalf("iterator %s", err)
+		}
+
+		var last []byte
+		for iter.First(); iter.Valid(); iter.Next() {
+			if last != nil && bytes.Compare(last, iter.Key()) >= 0 {
+				t.Fatalf("key %x out of order", iter.Key())
+			}
+
+			last = iter.Key()
+			if _, err := s.Get(last); err != nil {
+				t.Fatalf("get %s", err)
+			}
+		}
+
+		iter.Close()
+	}
+}
+
+func benchmarkKey(i int) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(i%1024))
+	binary.BigEndian.PutUint64(key[8:], uint64(i))
+	return key
+}
+
+func BenchmarkMemoryPut(b *testing.B) {
+	s, _ := Open()
+	defer s.Close()
+
+	val := make([]byte, 128)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := s.Put(benchmarkKey(i), val); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMemoryGet(b *testing.B) {
+	s, _ := Open()
+	defer s.Close()
+
+	num := 1 << 16
+	val := make([]byte, 128)
+
+	batch, _ := s.Batch()
+	for i := 0; i < num; i++ {
+		batch.Put(benchmarkKey(i), val)
+	}
+
+	if err := batch.Commit(); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if val, err := s.Get(benchmarkKey(i % num)); err != nil || val == nil {
+			b.Fatalf("get %d: %v", i, err)
+		}
+	}
+}
+
+func BenchmarkMemoryPutSnapshot(b *testing.B) {
+	s, _ := Open()
+	defer s.Close()
+
+	val := make([]byte, 128)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		// the first writes after a snapshot copy the nodes it shares
+		if i%1000 == 0 {
+			ss, _ := s.Snapshot()
+			ss.Release()
+		}
+
+		if err := s.Put(benchmarkKey(i), val); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
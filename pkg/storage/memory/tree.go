@@ -0,0 +1,293 @@
+package memory
+
+import (
+	"bytes"
+)
+
+// item a key & its value
+type item struct {
+	key, val []byte
+}
+
+// cow owner of the nodes a tree can modify in place.
+// nodes of other owners are copied before they are modified, so a frozen root, see Storage.freeze,
+// is a point-in-time view of the storage
+type cow struct {
+	_ byte
+}
+
+// node of a copy-on-write b-tree, leaves have no children
+type node struct {
+	cow      *cow
+	items    []item
+	children []*node
+}
+
+// search return the index of the least item >= key, true if it equals key
+func (n *node) search(key []byte) (int, bool) {
+	lo, hi := 0, len(n.items)
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		switch c := bytes.Compare(n.items[m].key, key); {
+		case c < 0:
+			lo = m + 1
+
+		case c > 0:
+			hi = m
+
+		default:
+			return m, true
+		}
+	}
+
+	return lo, false
+}
+
+func (n *node) leaf() bool {
+	return len(n.children) == 0
+}
+
+func (n *node) insertItem(i int, it item) {
+	n.items = append(n.items, item{})
+	copy(n.items[i+1:], n.items[i:])
+	n.items[i] = it
+}
+
+func (n *node) removeItem(i int) item {
+	it := n.items[i]
+	copy(n.items[i:], n.items[i+1:])
+	n.items[len(n.items)-1] = item{}
+	n.items = n.items[:len(n.items)-1]
+	return it
+}
+
+func (n *node) insertChild(i int, c *node) {
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = c
+}
+
+func (n *node) removeChild(i int) *node {
+	c := n.children[i]
+	copy(n.children[i:], n.children[i+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+	return c
+}
+
+// tree a b-tree whose nodes hold [max/2, max] items, except the root
+type tree struct {
+	root *node
+	cow  *cow
+	max  int
+}
+
+func (t *tree) get(key []byte) ([]byte, bool) {
+	for n := t.root; n != nil; {
+		i, found := n.search(key)
+		if found {
+			return n.items[i].val, true
+		}
+
+		if n.leaf() {
+			break
+		}
+
+		n = n.children[i]
+	}
+
+	return nil, false
+}
+
+func (t *tree) newNode() *node {
+	return &node{
+		cow:   t.cow,
+		items: make([]item, 0, t.max),
+	}
+}
+
+// mutable return n, or a copy of n if it is owned by others
+func (t *tree) mutable(n *node) *node {
+	if n.cow == t.cow {
+		return n
+	}
+
+	cp := t.newNode()
+	cp.items = append(cp.items, n.items...)
+	if !n.leaf() {
+		cp.children = append(make([]*node, 0, t.max+1), n.children...)
+	}
+
+	return cp
+}
+
+func (t *tree) mutableChild(n *node, i int) *node {
+	c := t.mutable(n.children[i])
+	n.children[i] = c
+	return c
+}
+
+// split move the items after i & their children to a new node, return the item at i & the new node
+func (t *tree) split(n *node, i int) (item, *node) {
+	it := n.items[i]
+
+	next := t.newNode()
+	next.items = append(next.items, n.items[i+1:]...)
+	for j := i; j < len(n.items); j++ {
+		n.items[j] = item{}
+	}
+
+	n.items = n.items[:i]
+
+	if !n.leaf() {
+		next.children = append(make([]*node, 0, t.max+1), n.children[i+1:]...)
+		for j := i + 1; j < len(n.children); j++ {
+			n.children[j] = nil
+		}
+
+		n.children = n.children[:i+1]
+	}
+
+	return it, next
+}
+
+// put set key to val, nodes are split on the way down so the insertion never goes back up
+func (t *tree) put(key, val []byte) {
+	if t.root == nil {
+		t.root = t.newNode()
+		t.root.items = append(t.root.items, item{key, val})
+		return
+	}
+
+	t.root = t.mutable(t.root)
+	if len(t.root.items) >= t.max {
+		it, next := t.split(t.root, t.max/2)
+
+		root := t.newNode()
+		root.items = append(root.items, it)
+		root.children = append(make([]*node, 0, t.max+1), t.root, next)
+		t.root = root
+	}
+
+	for n := t.root; ; {
+		i, found := n.search(key)
+		if found {
+			n.items[i].val = val
+			return
+		}
+
+		if n.leaf() {
+			n.insertItem(i, item{key, val})
+			return
+		}
+
+		if len(n.children[i].items) >= t.max {
+			it, next := t.split(t.mutableChild(n, i), t.max/2)
+			n.insertItem(i, it)
+			n.insertChild(i+1, next)
+
+			switch c := bytes.Compare(key, it.key); {
+			case c == 0:
+				n.items[i].val = val
+				return
+
+			case c > 0:
+				i++
+			}
+		}
+
+		n = t.mutableChild(n, i)
+	}
+}
+
+// del remove the key, false if the key does not exist.
+// children are grown on the way down so the removal never goes back up
+func (t *tree) del(key []byte) bool {
+	if _, ok := t.get(key); !ok {
+		return false
+	}
+
+	t.root = t.mutable(t.root)
+	for n := t.root; ; {
+		i, found := n.search(key)
+		if n.leaf() {
+			n.removeItem(i)
+			break
+		}
+
+		if len(n.children[i].items) <= t.max/2 {
+			t.grow(n, i)
+			continue
+		}
+
+		c := t.mutableChild(n, i)
+		if found {
+			// replaced by its predecessor
+			n.items[i] = t.removeMax(c)
+			break
+		}
+
+		n = c
+	}
+
+	if len(t.root.items) == 0 {
+		if t.root.leaf() {
+			t.root = nil
+		} else {
+			t.root = t.root.children[0]
+		}
+	}
+
+	return true
+}
+
+// removeMax remove the greatest item of the subtree
+func (t *tree) removeMax(n *node) item {
+	for !n.leaf() {
+		i := len(n.items)
+		if len(n.children[i].items) <= t.max/2 {
+			t.grow(n, i)
+			continue
+		}
+
+		n = t.mutableChild(n, i)
+	}
+
+	return n.removeItem(len(n.items) - 1)
+}
+
+// grow make the child i hold more than max/2 items, by moving one from a sibling or merging with one
+func (t *tree) grow(n *node, i int) {
+	if i > 0 && len(n.children[i-1].items) > t.max/2 {
+		c, left := t.mutableChild(n, i), t.mutableChild(n, i-1)
+		c.insertItem(0, n.items[i-1])
+		n.items[i-1] = left.removeItem(len(left.items) - 1)
+		if !left.leaf() {
+			c.insertChild(0, left.removeChild(len(left.children)-1))
+		}
+
+		return
+	}
+
+	if i < len(n.items) && len(n.children[i+1].items) > t.max/2 {
+		c, right := t.mutableChild(n, i), t.mutableChild(n, i+1)
+		c.items = append(c.items, n.items[i])
+		n.items[i] = right.removeItem(0)
+		if !right.leaf() {
+			c.children = append(c.children, right.removeChild(0))
+		}
+
+		return
+	}
+
+	// merge with the right sibling, the left one for the last child
+	if i == len(n.items) {
+		i--
+	}
+
+	c, right := t.mutableChild(n, i), n.children[i+1]
+	c.items = append(c.items, n.removeItem(i))
+	c.items = append(c.items, right.items...)
+	c.children = append(c.children, right.children...)
+	n.removeChild(i + 1)
+}
@@ -0,0 +1,276 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/dtynn/winston/pkg/storage"
+)
+
+// checkTree check the order of keys, the sizes of nodes & the depth of leaves, return the depth
+func checkTree(t *testing.T, n *node, max int, root bool, lo, hi []byte) int {
+	t.Helper()
+
+	if n == nil {
+		return 0
+	}
+
+	if len(n.items) > max || (!root && len(n.items) < max/2) || len(n.items) == 0 {
+		t.Fatalf("unexpected node size %d", len(n.items))
+	}
+
+	for i, it := range n.items {
+		if (lo != nil && bytes.Compare(it.key, lo) <= 0) || (hi != nil && bytes.Compare(it.key, hi) >= 0) {
+			t.Fatalf("key %q out of order", it.key)
+		}
+
+		if i > 0 && bytes.Compare(n.items[i-1].key, it.key) >= 0 {
+			t.Fatalf("key %q out of order", it.key)
+		}
+	}
+
+	if n.leaf() {
+		return 1
+	}
+
+	if len(n.children) != len(n.items)+1 {
+		t.Fatalf("expected %d children, got %d", len(n.items)+1, len(n.children))
+	}
+
+	depth := 0
+	for i, c := range n.children {
+		clo, chi := lo, hi
+		if i > 0 {
+			clo = n.items[i-1].key
+		}
+
+		if i < len(n.items) {
+			chi = n.items[i].key
+		}
+
+		d := checkTree(t, c, max, false, clo, chi)
+		if i > 0 && d != depth {
+			t.Fatalf("leaves at depth %d & %d", depth, d)
+		}
+
+		depth = d
+	}
+
+	return depth + 1
+}
+
+func TestTree(t *testing.T) {
+	for _, degree := range []int{2, 3, defaultDegree} {
+		t.Run(fmt.Sprint("Degree", degree), func(t *testing.T) {
+			testTree(t, degree)
+		})
+	}
+}
+
+func testTree(t *testing.T, degree int) {
+	s := setupTestStorage(t, Degree(degree))
+	defer teardownTestStorage(s)
+
+	model := map[string]string{}
+	keyOf := func(i int) string {
+		return fmt.Sprintf("k%03d", i)
+	}
+
+	// snapshots with their expected contents
+	type snap struct {
+		root *node
+		keys []string
+		vals map[string]string
+	}
+
+	var snaps []snap
+
+	sorted := func() []string {
+		keys := make([]string, 0, len(model))
+		for k := range model {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+		return keys
+	}
+
+	// writes through the storage or a transaction
+	write := func(w storage.Txn, round int) {
+		for i := 0; i < 10; i++ {
+			k := keyOf(rand.Intn(300))
+			if rand.Intn(3) == 0 {
+				delete(model, k)
+				if err := w.Del([]byte(k)); err != nil {
+					t.Fatalf("del %s", err)
+				}
+
+				continue
+			}
+
+			v := fmt.Sprint(round, i)
+			model[k] = v
+			if err := w.Put([]byte(k), []byte(v)); err != nil {
+				t.Fatalf("put %s", err)
+			}
+		}
+	}
+
+	errAbort := errors.New("abort")
+
+	for round := 0; round < 200; round++ {
+		if round%2 == 0 {
+			write(s, round)
+		} else if err := s.Update(func(txn storage.Txn) error {
+			write(txn, round)
+			return nil
+
+		}); err != nil {
+			t.Fatalf("update %s", err)
+		}
+
+		// changes of a failed transaction are dropped
+		if err := s.Update(func(txn storage.Txn) error {
+			for i := 0; i < 10; i++ {
+				txn.Put([]byte(keyOf(rand.Intn(300))), []byte("aborted"))
+				txn.Del([]byte(keyOf(rand.Intn(300))))
+			}
+
+			return errAbort
+
+		}); err != errAbort {
+			t.Fatalf("expected errAbort, got %v", err)
+		}
+
+		// iterators & snapshots freeze the root, later writes must not modify it
+		root, _ := s.freeze()
+		checkTree(t, root, s.t.max, true, nil, nil)
+
+		if round%20 == 0 {
+			vals := make(map[string]string, len(model))
+			for k, v := range model {
+				vals[k] = v
+			}
+
+			snaps = append(snaps, snap{root, sorted(), vals})
+		}
+
+		keys := sorted()
+
+		// random range, both directions
+		a, b := keyOf(rand.Intn(320)), keyOf(rand.Intn(320))
+		if a > b {
+			a, b = b, a
+		}
+
+		var expected []string
+		for _, k := range keys {
+			if k >= a && k < b {
+				expected = append(expected, k)
+			}
+		}
+
+		iter := newIterator(root, []byte(a), []byte(b))
+		var got []string
+		for iter.First(); iter.Valid(); iter.Next() {
+			if string(iter.Value()) != model[string(iter.Key())] {
+				t.Fatalf("unexpected value of %q", iter.Key())
+			}
+
+			got = append(got, string(iter.Key()))
+		}
+
+		if fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Fatalf("range [%s, %s): expected %v, got %v", a, b, expected, got)
+		}
+
+		got = got[:0]
+		for iter.Last(); iter.Valid(); iter.Prev() {
+			got = append([]string{string(iter.Key())}, got...)
+		}
+
+		if fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Fatalf("reversed range [%s, %s): expected %v, got %v", a, b, expected, got)
+		}
+
+		// seek then walk back & forth
+		seek := keyOf(rand.Intn(320))
+		idx := sort.SearchStrings(expected, seek)
+		if seek < a {
+			idx = 0
+		}
+
+		iter.Seek([]byte(seek))
+		if idx == len(expected) {
+			if iter.Valid() {
+				t.Fatalf("seek %s: expected invalid, got %q", seek, iter.Key())
+			}
+
+			continue
+		}
+
+		if string(iter.Key()) != expected[idx] {
+			t.Fatalf("seek %s: expected %s, got %q", seek, expected[idx], iter.Key())
+		}
+
+		if iter.Prev() != (idx > 0) || (idx > 0 && string(iter.Key()) != expected[idx-1]) {
+			t.Fatalf("seek %s & prev: unexpected %q", seek, iter.Key())
+		}
+	}
+
+	// snapshots are not affected by later writes
+	for _, sn := range snaps {
+		iter := newIterator(sn.root, nil, nil)
+		var got []string
+		for iter.Next() {
+			if string(iter.Value()) != sn.vals[string(iter.Key())] {
+				t.Fatalf("unexpected value of %q in snapshot", iter.Key())
+			}
+
+			got = append(got, string(iter.Key()))
+		}
+
+		if fmt.Sprint(got) != fmt.Sprint(sn.keys) {
+			t.Fatalf("snapshot: expected %v, got %v", sn.keys, got)
+		}
+	}
+}
+
+func TestTreeDepth(t *testing.T) {
+	s := setupTestStorage(t, Degree(2))
+	defer teardownTestStorage(s)
+
+	// sequential keys, then removing most of them
+	batch, _ := s.Batch()
+	num := 1 << 14
+	for i := 0; i < num; i++ {
+		batch.Put(benchmarkKey(i), nil)
+	}
+
+	if err := batch.Commit(); err != nil {
+		t.Fatalf("commit %s", err)
+	}
+
+	if depth := checkTree(t, s.t.root, s.t.max, true, nil, nil); depth > 14 {
+		t.Fatalf("expected a balanced tree, got depth %d for %d keys", depth, num)
+	}
+
+	batch, _ = s.Batch()
+	for i := 0; i < num; i++ {
+		if i%16 != 0 {
+			batch.Del(benchmarkKey(i))
+		}
+	}
+
+	if err := batch.Commit(); err != nil {
+		t.Fatalf("commit %s", err)
+	}
+
+	if depth := checkTree(t, s.t.root, s.t.max, true, nil, nil); depth > 10 {
+		t.Fatalf("expected a balanced tree, got depth %d for %d keys", depth, num/16)
+	}
+}
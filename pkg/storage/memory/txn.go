@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"github.com/dtynn/winston/pkg/storage"
+)
+
+// Txn read-write transaction on private copies of the nodes, published on commit
+type Txn struct {
+	t tree
+}
+
+// Get return value for specified key, return nil if key not found
+func (t *Txn) Get(key []byte) ([]byte, error) {
+	return lookup(&t.t, key), nil
+}
+
+// Put update the key with val
+func (t *Txn) Put(key, val []byte) error {
+	t.t.put(copyBytes(key), copyValue(val))
+	return nil
+}
+
+// Del delete the key
+func (t *Txn) Del(key []byte) error {
+	t.t.del(key)
+	return nil
+}
+
+// Update run fn in a transaction, writers are blocked until it returns
+func (s *Storage) Update(fn func(txn storage.Txn) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rw.RLock()
+	closed, t := s.closed, s.t
+	s.rw.RUnlock()
+
+	if closed {
+		return ErrClosed
+	}
+
+	// nodes owned by the storage are not modified, so the changes are dropped if fn fails
+	t.cow = new(cow)
+	txn := &Txn{
+		t: t,
+	}
+
+	if err := fn(txn); err != nil {
+		return err
+	}
+
+	s.rw.Lock()
+	s.t = txn.t
+	s.rw.Unlock()
+
+	return nil
+}
+
+// CompareAndSwap set the key to new if its value equals old
+func (s *Storage) CompareAndSwap(key, old, new []byte) (bool, error) {
+	var swapped bool
+	err := s.Update(func(txn storage.Txn) error {
+		var err error
+		swapped, err = storage.CompareAndSwap(txn, key, old, new)
+		return err
+	})
+
+	return swapped, err
+}
+
+// Increment add delta to the counter of the key
+func (s *Storage) Increment(key []byte, delta uint64) (uint64, error) {
+	var n uint64
+	err := s.Update(func(txn storage.Txn) error {
+		var err error
+		n, err = storage.Increment(txn, key, delta)
+		return err
+	})
+
+	return n, err
+}
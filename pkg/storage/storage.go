@@ -23,6 +23,20 @@ type Storage interface {
 
 	Batch() (Batch, error)
 
+	// CompareAndSwap set the key to new if its value equals old, nil old means the key does not exist,
+	// nil new deletes the key. return false if the value does not match
+	CompareAndSwap(key, old, new []byte) (bool, error)
+
+	// Increment add delta to the big endian uint64 counter of the key, a missing key counts from 0.
+	// return the new value
+	Increment(key []byte, delta uint64) (uint64, error)
+
+	// Update run fn in a read-write transaction, changes are committed if fn returns nil
+	Update(fn func(txn Txn) error) error
+
+	// Snapshot return a point-in-time view, which should be released after use
+	Snapshot() (Snapshot, error)
+
 	Close() error
 	GC() error
 }
@@ -50,3 +64,27 @@ type Batch interface {
 	Commit() error
 	Close() error
 }
+
+// Txn read-write transaction, values returned by Get are valid only inside the transaction
+type Txn interface {
+	// if key not found, just return nil value
+	Get(key []byte) ([]byte, error)
+
+	Put(key, val []byte) error
+	Del(key []byte) error
+}
+
+// Snapshot read-only point-in-time view of the storage, writes after it is taken are not visible
+type Snapshot interface {
+	// if key not found, just return nil value
+	Get(key []byte) ([]byte, error)
+
+	// if any key not found, just return nil value
+	MGet(keys ...[]byte) ([][]byte, error)
+
+	PrefixIterator(prefix []byte) (Iterator, error)
+	RangeIterator(start, end []byte) (Iterator, error)
+
+	// Release release the snapshot, iterators of the snapshot should be closed before
+	Release()
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dtynn/winston/pkg/storage"
+)
+
+// Snapshot point-in-time views
+func Snapshot(t *testing.T, s storage.Storage) {
+	before := map[string]string{
+		"s/a": "a1",
+		"s/b": "b1",
+		"s/c": "c1",
+	}
+
+	for k, v := range before {
+		if err := s.Put([]byte(k), []byte(v)); err != nil {
+			t.Fatalf("put %s: %s", k, err)
+		}
+	}
+
+	ss, err := s.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %s", err)
+	}
+
+	defer ss.Release()
+
+	// writes after the snapshot
+	if err := s.Put([]byte("s/a"), []byte("a2")); err != nil {
+		t.Fatalf("put: %s", err)
+	}
+
+	if err := s.Del([]byte("s/b")); err != nil {
+		t.Fatalf("del: %s", err)
+	}
+
+	if err := s.Put([]byte("s/d"), []byte("d2")); err != nil {
+		t.Fatalf("put: %s", err)
+	}
+
+	if _, err := s.Increment([]byte("s/e"), 1); err != nil {
+		t.Fatalf("increment: %s", err)
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		for _, k := range []string{"s/a", "s/b", "s/c", "s/d", "s/e"} {
+			val, err := ss.Get([]byte(k))
+			if err != nil {
+				t.Fatalf("get %s: %s", k, err)
+			}
+
+			v, ok := before[k]
+			if !ok {
+				if val != nil {
+					t.Fatalf("expected %s not to exist in the snapshot, got %q", k, val)
+				}
+
+				continue
+			}
+
+			if string(val) != v {
+				t.Fatalf("expected %s=%s in the snapshot, got %q", k, v, val)
+			}
+		}
+
+		val, err := s.Get([]byte("s/a"))
+		if err != nil {
+			t.Fatalf("get: %s", err)
+		}
+
+		if string(val) != "a2" {
+			t.Fatalf("expected the storage to be updated, got %q", val)
+		}
+	})
+
+	t.Run("MGet", func(t *testing.T) {
+		vals, err := ss.MGet([]byte("s/a"), []byte("s/b"), []byte("s/d"))
+		if err != nil {
+			t.Fatalf("mget: %s", err)
+		}
+
+		if string(vals[0]) != "a1" || string(vals[1]) != "b1" || vals[2] != nil {
+			t.Fatalf("unexpected values %q", vals)
+		}
+	})
+
+	collect := func(t *testing.T, iter storage.Iterator, err error) []string {
+		if err != nil {
+			t.Fatalf("iterator: %s", err)
+		}
+
+		defer iter.Close()
+
+		var kvs []string
+		for iter.First(); iter.Valid(); iter.Next() {
+			kvs = append(kvs, string(iter.Key())+"="+string(iter.Value()))
+		}
+
+		if err := iter.Err(); err != nil {
+			t.Fatalf("iterate: %s", err)
+		}
+
+		return kvs
+	}
+
+	t.Run("PrefixIterator", func(t *testing.T) {
+		iter, err := ss.PrefixIterator([]byte("s/"))
+		got := collect(t, iter, err)
+		if expected := []string{"s/a=a1", "s/b=b1", "s/c=c1"}; !reflect.DeepEqual(got, expected) {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+
+		iter, err = s.PrefixIterator([]byte("s/"))
+		got = collect(t, iter, err)
+		if len(got) != 4 {
+			t.Fatalf("expected the storage to be updated, got %v", got)
+		}
+	})
+
+	t.Run("RangeIterator", func(t *testing.T) {
+		iter, err := ss.RangeIterator([]byte("s/b"), []byte("s/e"))
+		got := collect(t, iter, err)
+		if expected := []string{"s/b=b1", "s/c=c1"}; !reflect.DeepEqual(got, expected) {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+
+		iter, err = ss.RangeIterator([]byte("s/b"), []byte("s/e"))
+		if err != nil {
+			t.Fatalf("iterator: %s", err)
+		}
+
+		iter.Last()
+		if !iter.Valid() || string(iter.Key()) != "s/c" {
+			t.Fatalf("expected last key s/c, got %q", iter.Key())
+		}
+
+		if err := iter.Close(); err != nil {
+			t.Fatalf("close: %s", err)
+		}
+
+		// the snapshot is still usable after its iterators are closed
+		if val, err := ss.Get([]byte("s/c")); err != nil || string(val) != "c1" {
+			t.Fatalf("expected c1, got %q, %v", val, err)
+		}
+	})
+}
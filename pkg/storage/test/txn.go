@@ -0,0 +1,220 @@
+package test
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/dtynn/winston/pkg/storage"
+)
+
+// Txn atomic read-modify-write operations
+func Txn(t *testing.T, s storage.Storage) {
+	t.Run("CompareAndSwap", func(t *testing.T) {
+		key := []byte("cas")
+
+		cases := []struct {
+			old     []byte
+			new     []byte
+			swapped bool
+			val     []byte
+		}{
+			{nil, []byte("v1"), true, []byte("v1")},
+			{nil, []byte("v2"), false, []byte("v1")},
+			{[]byte("v0"), []byte("v2"), false, []byte("v1")},
+			{[]byte("v1"), []byte("v2"), true, []byte("v2")},
+			{[]byte("v2"), nil, true, nil},
+			{[]byte("v2"), []byte("v3"), false, nil},
+		}
+
+		for i, c := range cases {
+			swapped, err := s.CompareAndSwap(key, c.old, c.new)
+			if err != nil {
+				t.Fatalf("#%d compare and swap: %s", i+1, err)
+			}
+
+			if swapped != c.swapped {
+				t.Fatalf("#%d expected swapped %v, got %v", i+1, c.swapped, swapped)
+			}
+
+			val, err := s.Get(key)
+			if err != nil {
+				t.Fatalf("#%d get: %s", i+1, err)
+			}
+
+			if !reflect.DeepEqual(val, c.val) {
+				t.Fatalf("#%d expected %q, got %q", i+1, c.val, val)
+			}
+		}
+	})
+
+	t.Run("Increment", func(t *testing.T) {
+		key := []byte("counter")
+
+		n, err := s.Increment(key, 5)
+		if err != nil {
+			t.Fatalf("increment: %s", err)
+		}
+
+		if n != 5 {
+			t.Fatalf("expected 5, got %d", n)
+		}
+
+		workers, rounds := 8, 50
+
+		var wg sync.WaitGroup
+		errs := make(chan error, workers)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < rounds; i++ {
+					if _, err := s.Increment(key, 1); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatalf("concurrent increment: %s", err)
+		}
+
+		val, err := s.Get(key)
+		if err != nil {
+			t.Fatalf("get: %s", err)
+		}
+
+		if got := binary.BigEndian.Uint64(val); got != uint64(5+workers*rounds) {
+			t.Fatalf("expected %d, got %d", 5+workers*rounds, got)
+		}
+
+		if err := s.Put(key, []byte("abc")); err != nil {
+			t.Fatalf("put: %s", err)
+		}
+
+		if _, err := s.Increment(key, 1); err != storage.ErrMalformedCounter {
+			t.Fatalf("expected ErrMalformedCounter, got %v", err)
+		}
+	})
+
+	t.Run("ConcurrentCompareAndSwap", func(t *testing.T) {
+		key := []byte("cas-counter")
+		workers, rounds := 8, 20
+
+		var wg sync.WaitGroup
+		errs := make(chan error, workers)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < rounds; {
+					old, err := s.Get(key)
+					if err != nil {
+						errs <- err
+						return
+					}
+
+					var n uint64
+					if old != nil {
+						n = binary.BigEndian.Uint64(old)
+					}
+
+					val := make([]byte, 8)
+					binary.BigEndian.PutUint64(val, n+1)
+
+					swapped, err := s.CompareAndSwap(key, old, val)
+					if err != nil {
+						errs <- err
+						return
+					}
+
+					if swapped {
+						i++
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatalf("concurrent compare and swap: %s", err)
+		}
+
+		val, err := s.Get(key)
+		if err != nil {
+			t.Fatalf("get: %s", err)
+		}
+
+		if got := binary.BigEndian.Uint64(val); got != uint64(workers*rounds) {
+			t.Fatalf("expected %d, got %d", workers*rounds, got)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		if err := s.Update(func(txn storage.Txn) error {
+			if err := txn.Put([]byte("u1"), []byte("a")); err != nil {
+				return err
+			}
+
+			if err := txn.Put([]byte("u2"), []byte("b")); err != nil {
+				return err
+			}
+
+			// writes are visible inside the transaction
+			val, err := txn.Get([]byte("u1"))
+			if err != nil {
+				return err
+			}
+
+			if string(val) != "a" {
+				t.Errorf("expected a, got %q", val)
+			}
+
+			return txn.Del([]byte("u2"))
+
+		}); err != nil {
+			t.Fatalf("update: %s", err)
+		}
+
+		vals, err := s.MGet([]byte("u1"), []byte("u2"))
+		if err != nil {
+			t.Fatalf("mget: %s", err)
+		}
+
+		if string(vals[0]) != "a" || vals[1] != nil {
+			t.Fatalf("unexpected values %q", vals)
+		}
+
+		rollback := errors.New("rollback")
+		if err := s.Update(func(txn storage.Txn) error {
+			if err := txn.Put([]byte("u1"), []byte("changed")); err != nil {
+				return err
+			}
+
+			if err := txn.Put([]byte("u3"), []byte("c")); err != nil {
+				return err
+			}
+
+			return rollback
+
+		}); err != rollback {
+			t.Fatalf("expected the error of fn, got %v", err)
+		}
+
+		vals, err = s.MGet([]byte("u1"), []byte("u3"))
+		if err != nil {
+			t.Fatalf("mget: %s", err)
+		}
+
+		if string(vals[0]) != "a" || vals[1] != nil {
+			t.Fatalf("expected changes to be discarded, got %q", vals)
+		}
+	})
+}
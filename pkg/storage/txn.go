@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	// ErrMalformedCounter the value of the key is not a uint64 counter
+	ErrMalformedCounter = errors.New("malformed counter")
+)
+
+// CompareAndSwap implement Storage.CompareAndSwap within the transaction
+func CompareAndSwap(txn Txn, key, old, new []byte) (bool, error) {
+	cur, err := txn.Get(key)
+	if err != nil {
+		return false, err
+	}
+
+	if (old == nil) != (cur == nil) || !bytes.Equal(cur, old) {
+		return false, nil
+	}
+
+	if new == nil {
+		return true, txn.Del(key)
+	}
+
+	return true, txn.Put(key, new)
+}
+
+// Increment implement Storage.Increment within the transaction, the counter wraps around on overflow
+func Increment(txn Txn, key []byte, delta uint64) (uint64, error) {
+	cur, err := txn.Get(key)
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+	if cur != nil {
+		if len(cur) != 8 {
+			return 0, ErrMalformedCounter
+		}
+
+		n = binary.BigEndian.Uint64(cur)
+	}
+
+	n += delta
+
+	val := make([]byte, 8)
+	binary.BigEndian.PutUint64(val, n)
+	return n, txn.Put(key, val)
+}